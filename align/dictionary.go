@@ -0,0 +1,86 @@
+package align
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/unixpickle/essentials"
+	"github.com/unixpickle/wordembed"
+)
+
+// A Pair associates a token in a source embedding with a
+// token in a target embedding.
+type Pair struct {
+	Source string
+	Target string
+}
+
+// SharedTokens creates an identity Pair for every token
+// present in both embeddings.
+//
+// This is the natural anchor set for aligning two versions
+// of an embedding trained on similar data.
+func SharedTokens(source, target wordembed.Embedding) []Pair {
+	targetTokens := map[string]bool{}
+	for _, token := range wordembed.EmbeddingTokens(target) {
+		targetTokens[token] = true
+	}
+	var res []Pair
+	for _, token := range wordembed.EmbeddingTokens(source) {
+		if targetTokens[token] {
+			res = append(res, Pair{Source: token, Target: token})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Source < res[j].Source
+	})
+	return res
+}
+
+// LoadDictionary reads a dictionary file.
+// See ReadDictionary for details on the format.
+func LoadDictionary(path string) ([]Pair, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, essentials.AddCtx("load dictionary", err)
+	}
+	defer f.Close()
+	return ReadDictionary(f)
+}
+
+// ReadDictionary reads a dictionary with one Pair per
+// line, where the source and target tokens are separated
+// by whitespace.
+//
+// A source token with multiple translations appears on
+// multiple lines.
+// Blank lines are ignored.
+func ReadDictionary(r io.Reader) (pairs []Pair, err error) {
+	defer essentials.AddCtxTo("read dictionary", &err)
+	scanner := bufio.NewScanner(r)
+	var lineNum int
+	for scanner.Scan() {
+		lineNum++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected 2 fields but got %d", lineNum,
+				len(fields))
+		}
+		pairs = append(pairs, Pair{Source: fields[0], Target: fields[1]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(pairs) == 0 {
+		return nil, errors.New("empty dictionary")
+	}
+	return pairs, nil
+}
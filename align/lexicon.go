@@ -0,0 +1,383 @@
+package align
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/essentials"
+	"github.com/unixpickle/wordembed"
+)
+
+const (
+	defaultNeighborhoodSize = 10
+	lexiconBatchSize        = 256
+)
+
+// A Translation is a candidate translation for a word.
+type Translation struct {
+	Token string
+
+	// Score is the CSLS score of the translation.
+	Score float64
+}
+
+// A Lexicon performs bilingual lexicon induction between a
+// source and a target embedding.
+//
+// Source words are mapped into the target space with a
+// Mapping, and translations are retrieved with
+// Cross-domain Similarity Local Scaling (CSLS), as in
+// Conneau et al., "Word Translation Without Parallel Data".
+// The CSLS score between a mapped source vector x and a
+// target vector y is 2*cos(x, y) - r(x) - r(y), where r
+// is the mean similarity of a vector to its K nearest
+// neighbors in the other language.
+// This penalizes hubs, which are close to many vectors.
+type Lexicon struct {
+	Source wordembed.Embedding
+	Target wordembed.Embedding
+
+	// Mapping maps the source space to the target space.
+	// It is set by Fit.
+	Mapping *Mapping
+
+	// K is the neighborhood size for CSLS.
+	K int
+
+	sourceTokens []string
+	targetTokens []string
+	sourceIDs    map[string]int
+	targetIDs    map[string]int
+	sourceRows   *anyvec.Matrix
+
+	mapped *wordembed.CosineSearcher
+	target *wordembed.CosineSearcher
+
+	sourceRadius []float64
+	targetRadius []float64
+}
+
+// NewLexicon creates a Lexicon.
+//
+// The sourceVocab and targetVocab arguments restrict the
+// words which can be translated and the candidate
+// translations, which is usually necessary for large
+// vocabularies, since the cost of refinement is
+// proportional to the product of the two vocabulary sizes.
+// If either list is nil, every token of the corresponding
+// embedding is used.
+//
+// If k is 0, a default of 10 is used.
+//
+// An error is returned if either vocabulary has no tokens
+// in the corresponding embedding.
+func NewLexicon(source, target wordembed.Embedding, sourceVocab, targetVocab []string,
+	k int) (lexicon *Lexicon, err error) {
+	defer essentials.AddCtxTo("new lexicon", &err)
+	if k == 0 {
+		k = defaultNeighborhoodSize
+	}
+	res := &Lexicon{Source: source, Target: target, K: k}
+	res.sourceTokens, res.sourceIDs = lexiconVocab(source, sourceVocab)
+	res.targetTokens, res.targetIDs = lexiconVocab(target, targetVocab)
+	if len(res.sourceTokens) == 0 {
+		return nil, errors.New("empty source vocabulary")
+	}
+	if len(res.targetTokens) == 0 {
+		return nil, errors.New("empty target vocabulary")
+	}
+	res.sourceRows = embeddingRows(source, res.sourceTokens)
+	res.target = wordembed.NewCosineSearcher(embeddingRows(target, res.targetTokens))
+	return res, nil
+}
+
+// Fit learns a Mapping from a seed dictionary (e.g. from
+// LoadDictionary) and then refines it.
+//
+// Each refinement iteration induces a dictionary of the
+// pairs of words which are mutual nearest neighbors under
+// CSLS, and recomputes the Mapping with Procrustes using
+// the seed and induced pairs.
+//
+// The scaled argument is passed to Procrustes.
+func (l *Lexicon) Fit(seed []Pair, iterations int, scaled bool) (err error) {
+	defer essentials.AddCtxTo("fit lexicon", &err)
+	if err := l.fitPairs(seed, scaled); err != nil {
+		return err
+	}
+	for i := 0; i < iterations; i++ {
+		pairs := append(append([]Pair{}, seed...), l.Induce()...)
+		if err := l.fitPairs(pairs, scaled); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Induce creates a dictionary of the source and target
+// words which are mutual nearest neighbors under CSLS.
+//
+// Fit must be called first.
+func (l *Lexicon) Induce() []Pair {
+	sourceBest := make([]int, len(l.sourceTokens))
+	l.sourceBatches(func(start int, sims []float64) {
+		numTargets := len(l.targetTokens)
+		for i := 0; i < len(sims)/numTargets; i++ {
+			sourceBest[start+i] = l.bestCSLS(sims[i*numTargets:(i+1)*numTargets],
+				l.targetRadius)
+		}
+	})
+	targetBest := make([]int, len(l.targetTokens))
+	l.targetBatches(func(start int, sims []float64) {
+		numSources := len(l.sourceTokens)
+		for i := 0; i < len(sims)/numSources; i++ {
+			targetBest[start+i] = l.bestCSLS(sims[i*numSources:(i+1)*numSources],
+				l.sourceRadius)
+		}
+	})
+	var res []Pair
+	for i, j := range sourceBest {
+		if j >= 0 && targetBest[j] == i {
+			res = append(res, Pair{Source: l.sourceTokens[i], Target: l.targetTokens[j]})
+		}
+	}
+	return res
+}
+
+// Translate finds the n best translations of a source
+// word under CSLS, sorted from best to worst.
+//
+// If the word is not in the source vocabulary, nil is
+// returned.
+// Fit must be called first.
+func (l *Lexicon) Translate(word string, n int) []Translation {
+	i, ok := l.sourceIDs[word]
+	if !ok {
+		return nil
+	}
+	row := l.mapped.Normalized.Data.Slice(i*l.Source.Dim(), (i+1)*l.Source.Dim())
+	sims := wordembed.NumericListFloat64(l.target.Similarities(row).Data())
+	scores := make([]float64, len(sims))
+	for j, sim := range sims {
+		scores[j] = 2*sim - l.sourceRadius[i] - l.targetRadius[j]
+	}
+	c := row.Creator()
+	ids, values := wordembed.TopK(c.MakeVectorData(c.MakeNumericList(scores)), n)
+	res := make([]Translation, len(ids))
+	for k, id := range ids {
+		res[k] = Translation{
+			Token: l.targetTokens[id],
+			Score: wordembed.NumericFloat64(values[k]),
+		}
+	}
+	return res
+}
+
+// Evaluate computes the precision of the translations for
+// a held-out dictionary.
+//
+// A source word may have multiple correct translations.
+// Source words which are not in the source vocabulary, or
+// which have no correct translation in the target
+// vocabulary, are not evaluated.
+// Fit must be called first.
+func (l *Lexicon) Evaluate(test []Pair) *LexiconResult {
+	answers := map[string]map[string]bool{}
+	var sources []string
+	for _, pair := range test {
+		if answers[pair.Source] == nil {
+			answers[pair.Source] = map[string]bool{}
+			sources = append(sources, pair.Source)
+		}
+		if _, ok := l.targetIDs[pair.Target]; ok {
+			answers[pair.Source][pair.Target] = true
+		}
+	}
+	res := &LexiconResult{Total: len(sources)}
+	var hits [3]int
+	for _, source := range sources {
+		if len(answers[source]) == 0 {
+			continue
+		}
+		translations := l.Translate(source, 10)
+		if translations == nil {
+			continue
+		}
+		res.Evaluated++
+		for rank, translation := range translations {
+			if answers[source][translation.Token] {
+				for i, k := range []int{1, 5, 10} {
+					if rank < k {
+						hits[i]++
+					}
+				}
+				break
+			}
+		}
+	}
+	if res.Evaluated > 0 {
+		res.PrecisionAt1 = float64(hits[0]) / float64(res.Evaluated)
+		res.PrecisionAt5 = float64(hits[1]) / float64(res.Evaluated)
+		res.PrecisionAt10 = float64(hits[2]) / float64(res.Evaluated)
+	}
+	return res
+}
+
+// LexiconResult stores the results of Lexicon.Evaluate.
+type LexiconResult struct {
+	// Total is the number of distinct source words in the
+	// test dictionary.
+	Total int
+
+	// Evaluated is the number of source words which could
+	// be evaluated.
+	Evaluated int
+
+	// PrecisionAt1, PrecisionAt5 and PrecisionAt10 are the
+	// fractions of evaluated words for which a correct
+	// translation was among the top 1, 5, or 10 results.
+	PrecisionAt1  float64
+	PrecisionAt5  float64
+	PrecisionAt10 float64
+}
+
+// String summarizes the results.
+func (l *LexiconResult) String() string {
+	return fmt.Sprintf("P@1=%.4f P@5=%.4f P@10=%.4f (evaluated %d/%d)", l.PrecisionAt1,
+		l.PrecisionAt5, l.PrecisionAt10, l.Evaluated, l.Total)
+}
+
+func (l *Lexicon) fitPairs(pairs []Pair, scaled bool) error {
+	mapping, err := Procrustes(l.Source, l.Target, pairs, scaled)
+	if err != nil {
+		return err
+	}
+	l.Mapping = mapping
+	l.mapped = wordembed.NewCosineSearcher(mapping.applyRows(l.sourceRows))
+	l.sourceRadius = make([]float64, len(l.sourceTokens))
+	l.sourceBatches(func(start int, sims []float64) {
+		l.neighborhoodRadii(sims, len(l.targetTokens), l.sourceRadius[start:])
+	})
+	l.targetRadius = make([]float64, len(l.targetTokens))
+	l.targetBatches(func(start int, sims []float64) {
+		l.neighborhoodRadii(sims, len(l.sourceTokens), l.targetRadius[start:])
+	})
+	return nil
+}
+
+// sourceBatches computes the similarities between batches
+// of mapped source vectors and every target vector.
+//
+// For each batch, f is called with the index of the first
+// source word and a row-major similarity matrix.
+func (l *Lexicon) sourceBatches(f func(start int, sims []float64)) {
+	similarityBatches(l.mapped.Normalized, l.target, f)
+}
+
+// targetBatches is like sourceBatches, but with the roles
+// of the source and target reversed.
+func (l *Lexicon) targetBatches(f func(start int, sims []float64)) {
+	similarityBatches(l.target.Normalized, l.mapped, f)
+}
+
+// neighborhoodRadii computes the mean similarity of each
+// row of a similarity matrix to its K nearest neighbors.
+func (l *Lexicon) neighborhoodRadii(sims []float64, cols int, out []float64) {
+	k := essentials.MinInt(l.K, cols)
+	if k <= 0 {
+		return
+	}
+	nearest := &similarityHeap{}
+	for i := 0; i < len(sims)/cols; i++ {
+		nearest.Float64Slice = nearest.Float64Slice[:0]
+		for _, sim := range sims[i*cols : (i+1)*cols] {
+			if nearest.Len() < k {
+				heap.Push(nearest, sim)
+			} else if sim > nearest.Float64Slice[0] {
+				nearest.Float64Slice[0] = sim
+				heap.Fix(nearest, 0)
+			}
+		}
+		var sum float64
+		for _, sim := range nearest.Float64Slice {
+			sum += sim
+		}
+		out[i] = sum / float64(k)
+	}
+}
+
+// bestCSLS finds the column with the best CSLS score for a
+// row of similarities.
+// Since the row's own radius is the same for every column,
+// it does not affect the result.
+func (l *Lexicon) bestCSLS(sims, radii []float64) int {
+	best := -1
+	var bestScore float64
+	for j, sim := range sims {
+		score := 2*sim - radii[j]
+		if best == -1 || score > bestScore {
+			best, bestScore = j, score
+		}
+	}
+	return best
+}
+
+func similarityBatches(queries *anyvec.Matrix, searcher *wordembed.CosineSearcher,
+	f func(start int, sims []float64)) {
+	for start := 0; start < queries.Rows; start += lexiconBatchSize {
+		end := essentials.MinInt(start+lexiconBatchSize, queries.Rows)
+		batch := &anyvec.Matrix{
+			Data: queries.Data.Slice(start*queries.Cols, end*queries.Cols),
+			Rows: end - start,
+			Cols: queries.Cols,
+		}
+		sims := searcher.BatchSimilarities(batch)
+		f(start, wordembed.NumericListFloat64(sims.Data.Data()))
+	}
+}
+
+// similarityHeap is a min-heap of similarities, used to
+// keep track of the K largest similarities in a row.
+type similarityHeap struct {
+	sort.Float64Slice
+}
+
+func (s *similarityHeap) Push(x interface{}) {
+	s.Float64Slice = append(s.Float64Slice, x.(float64))
+}
+
+func (s *similarityHeap) Pop() interface{} {
+	res := s.Float64Slice[len(s.Float64Slice)-1]
+	s.Float64Slice = s.Float64Slice[:len(s.Float64Slice)-1]
+	return res
+}
+
+// lexiconVocab finds the tokens of a vocabulary which are
+// present in an embedding, without duplicates.
+func lexiconVocab(e wordembed.Embedding, vocab []string) ([]string, map[string]int) {
+	present := tokenSet(e)
+	if vocab == nil {
+		vocab = wordembed.EmbeddingTokens(e)
+	}
+	var tokens []string
+	ids := map[string]int{}
+	for _, token := range vocab {
+		if _, ok := ids[token]; !ok && present[token] {
+			ids[token] = len(tokens)
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, ids
+}
+
+func embeddingRows(e wordembed.Embedding, tokens []string) *anyvec.Matrix {
+	rows := make([]anyvec.Vector, len(tokens))
+	for i, token := range tokens {
+		rows[i] = e.Embed(token)
+	}
+	c := e.EmbedID(0).Creator()
+	return &anyvec.Matrix{Data: c.Concat(rows...), Rows: len(tokens), Cols: e.Dim()}
+}
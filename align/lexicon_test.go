@@ -0,0 +1,125 @@
+package align
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/wordembed"
+)
+
+func TestLexicon(t *testing.T) {
+	source := randomEmbedding(200, 8)
+	target := transformEmbedding(source, randomOrthogonal(8), 1)
+	for i, token := range target.Tokens {
+		target.Tokens[i] = strings.Replace(token, "token", "word", 1)
+	}
+
+	var seed, test []Pair
+	for i := 0; i < 200; i++ {
+		pair := Pair{Source: fmt.Sprintf("token%05d", i), Target: fmt.Sprintf("word%05d", i)}
+		if i < 10 {
+			seed = append(seed, pair)
+		} else {
+			test = append(test, pair)
+		}
+	}
+	test = append(test, Pair{Source: "missing", Target: "word00000"})
+
+	lexicon, err := NewLexicon(source, target, nil, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := lexicon.Fit(seed, 2, false); err != nil {
+		t.Fatal(err)
+	}
+
+	translations := lexicon.Translate("token00123", 3)
+	if len(translations) != 3 || translations[0].Token != "word00123" {
+		t.Errorf("unexpected translations: %v", translations)
+	}
+	if lexicon.Translate("missing", 3) != nil {
+		t.Error("expected nil translations for missing word")
+	}
+
+	if induced := lexicon.Induce(); len(induced) != 200 {
+		t.Errorf("expected 200 induced pairs but got %d", len(induced))
+	}
+
+	result := lexicon.Evaluate(test)
+	if result.Total != 191 || result.Evaluated != 190 {
+		t.Errorf("unexpected counts: %d/%d", result.Evaluated, result.Total)
+	}
+	if result.PrecisionAt1 != 1 || result.PrecisionAt5 != 1 || result.PrecisionAt10 != 1 {
+		t.Errorf("unexpected precision: %s", result)
+	}
+}
+
+func TestLexiconEmptyVocab(t *testing.T) {
+	source := randomEmbedding(10, 4)
+	target := randomEmbedding(10, 4)
+	if _, err := NewLexicon(source, target, []string{"missing"}, nil, 0); err == nil {
+		t.Error("expected error for empty source vocabulary")
+	}
+	if _, err := NewLexicon(source, target, nil, []string{}, 0); err == nil {
+		t.Error("expected error for empty target vocabulary")
+	}
+}
+
+func TestLexiconCSLS(t *testing.T) {
+	source := randomEmbedding(30, 4)
+	target := transformEmbedding(source, randomOrthogonal(4), 1)
+	lexicon, err := NewLexicon(source, target, nil, []string{"token00001", "token00002",
+		"token00003", "token00004"}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := lexicon.Fit(SharedTokens(source, target), 0, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Compute the CSLS scores by brute force.
+	cos := func(v1, v2 []float64) float64 {
+		var dot, norm1, norm2 float64
+		for i, x := range v1 {
+			dot += x * v2[i]
+			norm1 += x * x
+			norm2 += v2[i] * v2[i]
+		}
+		return dot / math.Sqrt(norm1*norm2)
+	}
+	radius := func(v []float64, others []string, e func(string) []float64) float64 {
+		var sims []float64
+		for _, other := range others {
+			sims = append(sims, cos(v, e(other)))
+		}
+		var best1, best2 float64 = -2, -2
+		for _, sim := range sims {
+			if sim > best1 {
+				best1, best2 = sim, best1
+			} else if sim > best2 {
+				best2 = sim
+			}
+		}
+		return (best1 + best2) / 2
+	}
+	mappedVec := func(token string) []float64 {
+		mapped := lexicon.Mapping.Apply(source.Embed(token))
+		return wordembed.NumericListFloat64(mapped.Data())
+	}
+	targetVec := func(token string) []float64 {
+		return wordembed.NumericListFloat64(target.Embed(token).Data())
+	}
+	query := "token00007"
+	for _, translation := range lexicon.Translate(query, 4) {
+		x := mappedVec(query)
+		y := targetVec(translation.Token)
+		expected := 2*cos(x, y) - radius(x, lexicon.targetTokens, targetVec) -
+			radius(y, lexicon.sourceTokens, mappedVec)
+		if math.Abs(expected-translation.Score) > 1e-8 {
+			t.Errorf("%s: expected score %f but got %f", translation.Token, expected,
+				translation.Score)
+		}
+	}
+}
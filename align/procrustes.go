@@ -0,0 +1,241 @@
+// Package align maps word embeddings into each other's
+// vector spaces.
+package align
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/essentials"
+	"github.com/unixpickle/wordembed"
+	"github.com/unixpickle/wordembed/glove"
+)
+
+// procrustesBatchSize is the number of vectors to
+// multiply at once when fitting and applying Mappings.
+const procrustesBatchSize = 1024
+
+// A Mapping is a linear map from a source vector space to
+// a target vector space.
+type Mapping struct {
+	// Matrix is an orthogonal matrix.
+	// A source vector x, viewed as a row vector, is mapped
+	// to Scale*x*Matrix.
+	Matrix *anyvec.Matrix
+
+	// Scale is a global scale factor.
+	Scale float64
+}
+
+// Procrustes solves the orthogonal Procrustes problem,
+// finding the orthogonal Mapping which best maps the
+// anchors' source vectors to their target vectors in the
+// least-squares sense.
+//
+// If scaled is true, the Mapping's Scale is also fit to
+// the anchors.
+// Otherwise, it is 1.
+//
+// Anchors with a token missing from either embedding are
+// ignored.
+// The embeddings must have the same dimensionality.
+func Procrustes(source, target wordembed.Embedding, anchors []Pair,
+	scaled bool) (mapping *Mapping, err error) {
+	defer essentials.AddCtxTo("procrustes", &err)
+	if source.Dim() != target.Dim() {
+		return nil, errors.New("dimension mismatch")
+	}
+	pairs := filterAnchors(source, target, anchors)
+	if len(pairs) == 0 {
+		return nil, errors.New("no anchors in vocabulary")
+	}
+
+	// Accumulate the cross-covariance in batches, so that
+	// the anchor vectors are never all in memory at once.
+	c := source.EmbedID(0).Creator()
+	dim := source.Dim()
+	cross := &anyvec.Matrix{Data: c.MakeVector(dim * dim), Rows: dim, Cols: dim}
+	var sourceSquares float64
+	for start := 0; start < len(pairs); start += procrustesBatchSize {
+		batch := pairs[start:essentials.MinInt(start+procrustesBatchSize, len(pairs))]
+		var sourceRows, targetRows []anyvec.Vector
+		for _, pair := range batch {
+			sourceRows = append(sourceRows, source.Embed(pair.Source))
+			targetRows = append(targetRows, target.Embed(pair.Target))
+		}
+		x := &anyvec.Matrix{Data: c.Concat(sourceRows...), Rows: len(batch), Cols: dim}
+		y := &anyvec.Matrix{Data: c.Concat(targetRows...), Rows: len(batch), Cols: dim}
+		cross.Product(true, false, c.MakeNumeric(1), x, y, c.MakeNumeric(1))
+		sourceSquares += wordembed.NumericFloat64(x.Data.Dot(x.Data))
+	}
+
+	u, s, v := svd(wordembed.NumericListFloat64(cross.Data.Data()), dim)
+	w := make([]float64, dim*dim)
+	for i := 0; i < dim; i++ {
+		for j := 0; j < dim; j++ {
+			var sum float64
+			for k := 0; k < dim; k++ {
+				sum += u[i*dim+k] * v[j*dim+k]
+			}
+			w[i*dim+j] = sum
+		}
+	}
+
+	res := &Mapping{
+		Matrix: &anyvec.Matrix{
+			Data: c.MakeVectorData(c.MakeNumericList(w)),
+			Rows: dim,
+			Cols: dim,
+		},
+		Scale: 1,
+	}
+	if scaled {
+		var singularSum float64
+		for _, value := range s {
+			singularSum += value
+		}
+		if sourceSquares != 0 {
+			res.Scale = singularSum / sourceSquares
+		}
+	}
+	return res, nil
+}
+
+// Apply maps a source vector into the target space.
+func (m *Mapping) Apply(vec anyvec.Vector) anyvec.Vector {
+	return m.applyRows(&anyvec.Matrix{Data: vec, Rows: 1, Cols: vec.Len()}).Data
+}
+
+// ApplyEmbedding maps every vector of an embedding into
+// the target space, including the vector for unknown
+// tokens.
+func (m *Mapping) ApplyEmbedding(e wordembed.Embedding) *glove.Embedding {
+	tokens := wordembed.TokenSet(append([]string{}, wordembed.EmbeddingTokens(e)...))
+	sort.Strings(tokens)
+	numRows := tokens.NumIDs()
+	c := e.EmbedID(0).Creator()
+	res := &anyvec.Matrix{
+		Data: c.MakeVector(numRows * m.Matrix.Cols),
+		Rows: numRows,
+		Cols: m.Matrix.Cols,
+	}
+	for start := 0; start < numRows; start += procrustesBatchSize {
+		end := essentials.MinInt(start+procrustesBatchSize, numRows)
+		var rows []anyvec.Vector
+		for id := start; id < end; id++ {
+			if id == len(tokens) {
+				rows = append(rows, e.EmbedID(len(tokens)))
+			} else {
+				rows = append(rows, e.Embed(tokens[id]))
+			}
+		}
+		batch := &anyvec.Matrix{Data: c.Concat(rows...), Rows: len(rows), Cols: e.Dim()}
+		res.Data.SetSlice(start*res.Cols, m.applyRows(batch).Data)
+	}
+	return &glove.Embedding{Tokens: tokens, Vectors: res}
+}
+
+func (m *Mapping) applyRows(rows *anyvec.Matrix) *anyvec.Matrix {
+	c := rows.Data.Creator()
+	res := &anyvec.Matrix{
+		Data: c.MakeVector(rows.Rows * m.Matrix.Cols),
+		Rows: rows.Rows,
+		Cols: m.Matrix.Cols,
+	}
+	res.Product(false, false, c.MakeNumeric(m.Scale), rows, m.Matrix, c.MakeNumeric(0))
+	return res
+}
+
+// A Displacement measures how far a token's vector moved
+// between two aligned embeddings.
+type Displacement struct {
+	Token string
+
+	// Distance is the cosine distance (one minus the cosine
+	// similarity) between the token's vectors.
+	Distance float64
+}
+
+// Displacements computes the Displacement of every token
+// present in both embeddings, which should already be in
+// the same vector space.
+//
+// The results are sorted from most to least displaced, so
+// the words whose meaning drifted the most come first.
+func Displacements(aligned, target wordembed.Embedding) []Displacement {
+	var res []Displacement
+	for _, pair := range SharedTokens(aligned, target) {
+		v1 := wordembed.NumericListFloat64(aligned.Embed(pair.Source).Data())
+		v2 := wordembed.NumericListFloat64(target.Embed(pair.Target).Data())
+		var dot, norm1, norm2 float64
+		for i, x := range v1 {
+			dot += x * v2[i]
+			norm1 += x * x
+			norm2 += v2[i] * v2[i]
+		}
+		var sim float64
+		if norm1 != 0 && norm2 != 0 {
+			sim = dot / math.Sqrt(norm1*norm2)
+		}
+		res = append(res, Displacement{Token: pair.Source, Distance: 1 - sim})
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Distance > res[j].Distance
+	})
+	return res
+}
+
+// A Result is the outcome of aligning two embeddings.
+type Result struct {
+	// Mapping maps the source space to the target space.
+	Mapping *Mapping
+
+	// Aligned is the source embedding, mapped into the
+	// target space.
+	Aligned *glove.Embedding
+
+	// Displacements lists the displacement of every token
+	// shared by the two embeddings, as computed by the
+	// Displacements function.
+	Displacements []Displacement
+}
+
+// Align maps a source embedding into the space of a target
+// embedding using Procrustes.
+func Align(source, target wordembed.Embedding, anchors []Pair, scaled bool) (*Result,
+	error) {
+	mapping, err := Procrustes(source, target, anchors, scaled)
+	if err != nil {
+		return nil, err
+	}
+	aligned := mapping.ApplyEmbedding(source)
+	return &Result{
+		Mapping:       mapping,
+		Aligned:       aligned,
+		Displacements: Displacements(aligned, target),
+	}, nil
+}
+
+// filterAnchors removes the anchors which are not in the
+// vocabularies of the embeddings.
+func filterAnchors(source, target wordembed.Embedding, anchors []Pair) []Pair {
+	sourceVocab := tokenSet(source)
+	targetVocab := tokenSet(target)
+	var res []Pair
+	for _, pair := range anchors {
+		if sourceVocab[pair.Source] && targetVocab[pair.Target] {
+			res = append(res, pair)
+		}
+	}
+	return res
+}
+
+func tokenSet(e wordembed.Embedding) map[string]bool {
+	res := map[string]bool{}
+	for _, token := range wordembed.EmbeddingTokens(e) {
+		res[token] = true
+	}
+	return res
+}
@@ -0,0 +1,218 @@
+package align
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvec64"
+	"github.com/unixpickle/wordembed"
+	"github.com/unixpickle/wordembed/glove"
+	"github.com/unixpickle/wordembed/internal/embedtest"
+)
+
+func TestAlignRotation(t *testing.T) {
+	source := embedtest.RandomEmbedding(anyvec64.CurrentCreator(), 50, 4)
+	rotation := randomOrthogonal(4)
+	target := transformEmbedding(source, rotation, 2)
+
+	for _, scaled := range []bool{false, true} {
+		res, err := Align(source, target, SharedTokens(source, target), scaled)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedScale := 1.0
+		if scaled {
+			expectedScale = 2
+		}
+		if math.Abs(res.Mapping.Scale-expectedScale) > 1e-8 {
+			t.Errorf("expected scale %f but got %f", expectedScale, res.Mapping.Scale)
+		}
+		actual := res.Mapping.Matrix.Data.Data().([]float64)
+		for i, x := range rotation {
+			if math.Abs(x-actual[i]) > 1e-8 {
+				t.Fatalf("expected matrix %v but got %v", rotation, actual)
+			}
+		}
+		if len(res.Displacements) != 50 {
+			t.Fatalf("expected 50 displacements but got %d", len(res.Displacements))
+		}
+		for _, d := range res.Displacements {
+			if math.Abs(d.Distance) > 1e-8 {
+				t.Errorf("unexpected displacement for %s: %f", d.Token, d.Distance)
+			}
+		}
+	}
+}
+
+func TestAlignDrift(t *testing.T) {
+	source := embedtest.RandomEmbedding(anyvec64.CurrentCreator(), 100, 8)
+	rotation := randomOrthogonal(8)
+	target := transformEmbedding(source, rotation, 1)
+
+	// Move one word in the target embedding.
+	drifted := target.Tokens.ID("token00042")
+	data := target.Vectors.Data.Data().([]float64)
+	copy(data[drifted*8:], randomMatrix(1, 8))
+	target.Vectors.Data.SetData(data)
+
+	res, err := Align(source, target, SharedTokens(source, target), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Displacements[0].Token != "token00042" {
+		t.Errorf("expected drifted word first but got %v", res.Displacements[:3])
+	}
+	if res.Displacements[1].Distance > 0.1 {
+		t.Errorf("displacement too large: %v", res.Displacements[1])
+	}
+}
+
+func TestAlignBatches(t *testing.T) {
+	// Use enough tokens to span multiple batches.
+	source := embedtest.RandomEmbedding(anyvec64.CurrentCreator(), procrustesBatchSize*2+100, 3)
+	rotation := randomOrthogonal(3)
+	target := transformEmbedding(source, rotation, 2)
+
+	res, err := Align(source, target, SharedTokens(source, target), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(res.Mapping.Scale-2) > 1e-8 {
+		t.Errorf("expected scale 2 but got %f", res.Mapping.Scale)
+	}
+	expected := target.Vectors.Data.Data().([]float64)
+	actual := res.Aligned.Vectors.Data.Data().([]float64)
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d components but got %d", len(expected), len(actual))
+	}
+	for i, x := range expected {
+		if math.Abs(x-actual[i]) > 1e-8 {
+			t.Fatalf("component %d: expected %f but got %f", i, x, actual[i])
+		}
+	}
+}
+
+func TestProcrustesSeedDictionary(t *testing.T) {
+	source := embedtest.RandomEmbedding(anyvec64.CurrentCreator(), 20, 3)
+	rotation := randomOrthogonal(3)
+	target := transformEmbedding(source, rotation, 1)
+
+	// Rename the target tokens, so that only a dictionary
+	// can link the two embeddings.
+	for i, token := range target.Tokens {
+		target.Tokens[i] = strings.Replace(token, "token", "word", 1)
+	}
+	pairs, err := ReadDictionary(strings.NewReader("token00001 word00001\n\n" +
+		"token00002\tword00002\ntoken00003 word00003\ntoken00004 missing\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pairs) != 4 {
+		t.Fatalf("unexpected pairs: %v", pairs)
+	}
+	mapping, err := Procrustes(source, target, pairs, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapped := mapping.Apply(source.Embed("token00010")).Data().([]float64)
+	expected := target.Embed("word00010").Data().([]float64)
+	for i, x := range expected {
+		if math.Abs(x-mapped[i]) > 1e-8 {
+			t.Fatalf("expected %v but got %v", expected, mapped)
+		}
+	}
+
+	if _, err := Procrustes(source, target, []Pair{{"a", "b"}}, false); err == nil {
+		t.Error("expected error for missing anchors")
+	}
+	if _, err := ReadDictionary(strings.NewReader("a b c\n")); err == nil {
+		t.Error("expected error for bad line")
+	}
+}
+
+func TestSVD(t *testing.T) {
+	for _, n := range []int{1, 3, 10} {
+		for _, rank := range []int{n, (n + 1) / 2} {
+			// Build a random matrix with the given rank.
+			a := randomMatrix(n, rank)
+			b := randomMatrix(rank, n)
+			m := matMul(a, b, n, rank, n)
+
+			u, s, v := svd(m, n)
+			checkOrthogonal(t, u, n)
+			checkOrthogonal(t, v, n)
+			for i := 0; i < n; i++ {
+				for j := 0; j < n; j++ {
+					var sum float64
+					for k := 0; k < n; k++ {
+						sum += u[i*n+k] * s[k] * v[j*n+k]
+					}
+					if math.Abs(sum-m[i*n+j]) > 1e-8 {
+						t.Fatalf("n=%d rank=%d: bad reconstruction", n, rank)
+					}
+				}
+			}
+		}
+	}
+}
+
+func checkOrthogonal(t *testing.T, m []float64, n int) {
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			var dot float64
+			for k := 0; k < n; k++ {
+				dot += m[k*n+i] * m[k*n+j]
+			}
+			expected := 0.0
+			if i == j {
+				expected = 1
+			}
+			if math.Abs(dot-expected) > 1e-8 {
+				t.Fatalf("matrix is not orthogonal: %v", m)
+			}
+		}
+	}
+}
+
+// transformEmbedding maps the rows of an embedding
+// through scale*x*m.
+func transformEmbedding(e *glove.Embedding, m []float64, scale float64) *glove.Embedding {
+	dim := e.Vectors.Cols
+	rows := matMul(e.Vectors.Data.Data().([]float64), m, e.Vectors.Rows, dim, dim)
+	for i := range rows {
+		rows[i] *= scale
+	}
+	return &glove.Embedding{
+		Tokens: append(wordembed.TokenSet{}, e.Tokens...),
+		Vectors: &anyvec.Matrix{
+			Data: anyvec64.MakeVectorData(rows),
+			Rows: e.Vectors.Rows,
+			Cols: dim,
+		},
+	}
+}
+
+func randomOrthogonal(n int) []float64 {
+	u, _, _ := svd(randomMatrix(n, n), n)
+	return u
+}
+
+func randomMatrix(rows, cols int) []float64 {
+	vec := anyvec64.MakeVector(rows * cols)
+	anyvec.Rand(vec, anyvec.Normal, nil)
+	return vec.Data().([]float64)
+}
+
+func matMul(a, b []float64, rows, inner, cols int) []float64 {
+	res := make([]float64, rows*cols)
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			for k := 0; k < inner; k++ {
+				res[i*cols+j] += a[i*inner+k] * b[k*cols+j]
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,123 @@
+package align
+
+import "math"
+
+const (
+	svdEpsilon   = 1e-12
+	maxSVDSweeps = 100
+)
+
+// svd computes the singular value decomposition
+// M = U*diag(S)*V^T of a square, row-major matrix, using
+// one-sided Jacobi rotations.
+//
+// The results U and V are row-major orthogonal matrices.
+// If M is rank deficient, the columns of U corresponding
+// to zero singular values are filled in arbitrarily, so
+// that U is still orthogonal.
+func svd(m []float64, n int) (u, s, v []float64) {
+	a := append([]float64{}, m...)
+	v = make([]float64, n*n)
+	for i := 0; i < n; i++ {
+		v[i*n+i] = 1
+	}
+
+	for sweep := 0; sweep < maxSVDSweeps; sweep++ {
+		rotated := false
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				var alpha, beta, gamma float64
+				for i := 0; i < n; i++ {
+					x, y := a[i*n+p], a[i*n+q]
+					alpha += x * x
+					beta += y * y
+					gamma += x * y
+				}
+				if math.Abs(gamma) <= svdEpsilon*math.Sqrt(alpha*beta) || gamma == 0 {
+					continue
+				}
+				rotated = true
+				zeta := (beta - alpha) / (2 * gamma)
+				t := 1 / (math.Abs(zeta) + math.Sqrt(1+zeta*zeta))
+				if zeta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(1+t*t)
+				sn := c * t
+				rotateColumns(a, n, p, q, c, sn)
+				rotateColumns(v, n, p, q, c, sn)
+			}
+		}
+		if !rotated {
+			break
+		}
+	}
+
+	s = make([]float64, n)
+	u = make([]float64, n*n)
+	var zeroCols []int
+	for j := 0; j < n; j++ {
+		var norm float64
+		for i := 0; i < n; i++ {
+			norm += a[i*n+j] * a[i*n+j]
+		}
+		s[j] = math.Sqrt(norm)
+		if s[j] <= svdEpsilon {
+			s[j] = 0
+			zeroCols = append(zeroCols, j)
+			continue
+		}
+		for i := 0; i < n; i++ {
+			u[i*n+j] = a[i*n+j] / s[j]
+		}
+	}
+	completeBasis(u, n, zeroCols)
+	return
+}
+
+func rotateColumns(m []float64, n, p, q int, c, s float64) {
+	for i := 0; i < n; i++ {
+		x, y := m[i*n+p], m[i*n+q]
+		m[i*n+p] = c*x - s*y
+		m[i*n+q] = s*x + c*y
+	}
+}
+
+// completeBasis fills in the given columns of a matrix
+// with unit vectors orthogonal to every other column,
+// using Gram-Schmidt on the standard basis.
+func completeBasis(m []float64, n int, cols []int) {
+	basisIdx := 0
+	for _, col := range cols {
+		for ; basisIdx < n; basisIdx++ {
+			vec := make([]float64, n)
+			vec[basisIdx] = 1
+			// Orthogonalizing twice keeps the result accurate.
+			for pass := 0; pass < 2; pass++ {
+				for j := 0; j < n; j++ {
+					if j == col {
+						continue
+					}
+					var proj float64
+					for i := 0; i < n; i++ {
+						proj += m[i*n+j] * vec[i]
+					}
+					for i := 0; i < n; i++ {
+						vec[i] -= proj * m[i*n+j]
+					}
+				}
+			}
+			var norm float64
+			for _, x := range vec {
+				norm += x * x
+			}
+			if norm = math.Sqrt(norm); norm > 1e-6 {
+				for i, x := range vec {
+					m[i*n+col] = x / norm
+				}
+				basisIdx++
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package analogy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/unixpickle/essentials"
+)
+
+// evalBatchSize is the number of questions which are
+// solved at once during evaluation.
+const evalBatchSize = 256
+
+// A Question is an analogy query with a set of accepted
+// answers.
+type Question struct {
+	Query   Query
+	Answers []string
+}
+
+// A Section is a named group of questions, such as the
+// "capital-common-countries" section of the Google
+// analogy dataset.
+type Section struct {
+	Name      string
+	Questions []*Question
+}
+
+// LoadQuestions reads a file in the format of the Google
+// questions-words.txt dataset.
+//
+// See ReadQuestions for details.
+func LoadQuestions(path string, lowercase bool) ([]*Section, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, essentials.AddCtx("load questions", err)
+	}
+	defer f.Close()
+	return ReadQuestions(f, lowercase)
+}
+
+// ReadQuestions reads questions in the format of the
+// Google questions-words.txt dataset.
+//
+// Each section starts with a line of the form ": name".
+// Every other line contains four words "a b c d", meaning
+// that a is to b as c is to d.
+//
+// If lowercase is true, words are converted to lower case,
+// matching the default wordembed.Tokenizer.
+// This matters for the Google dataset, which capitalizes
+// the names of countries, cities, and states.
+// Otherwise, words are used verbatim.
+func ReadQuestions(r io.Reader, lowercase bool) (sections []*Section, err error) {
+	defer essentials.AddCtxTo("read questions", &err)
+	scanner := bufio.NewScanner(r)
+	for lineIdx := 1; scanner.Scan(); lineIdx++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			name := strings.TrimSpace(strings.TrimPrefix(line, ":"))
+			sections = append(sections, &Section{Name: name})
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("line %d: expected 4 words", lineIdx)
+		}
+		if len(sections) == 0 {
+			return nil, fmt.Errorf("line %d: question before first section", lineIdx)
+		}
+		if lowercase {
+			for i, field := range fields {
+				fields[i] = strings.ToLower(field)
+			}
+		}
+		section := sections[len(sections)-1]
+		section.Questions = append(section.Questions, &Question{
+			Query:   Query{A: fields[0], B: fields[1], C: fields[2]},
+			Answers: []string{fields[3]},
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return sections, nil
+}
+
+// LoadBATS reads the Bigger Analogy Test Set from a
+// directory.
+//
+// Every .txt file under the directory (except for README
+// files) is read as a section, named after the file.
+// Each line of a file contains a word and its related
+// words, separated by whitespace, where multiple related
+// words are separated by "/".
+//
+// For every ordered pair of distinct lines (a, b) and
+// (c, d), a question "a is to b as c is to ?" is created.
+// The first related word on a line is used in queries,
+// and any of the related words are accepted as answers.
+func LoadBATS(dir string) (sections []*Section, err error) {
+	defer essentials.AddCtxTo("load BATS", &err)
+	var paths []string
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name := strings.ToLower(info.Name())
+		if !info.IsDir() && strings.HasSuffix(name, ".txt") &&
+			!strings.HasPrefix(name, "readme") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		section, err := loadBATSSection(path)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, section)
+	}
+	return sections, nil
+}
+
+func loadBATSSection(path string) (*Section, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	type pair struct {
+		Word    string
+		Related []string
+	}
+	var pairs []pair
+	for lineIdx, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s: line %d: expected 2 fields", path, lineIdx+1)
+		}
+		pairs = append(pairs, pair{Word: fields[0], Related: strings.Split(fields[1], "/")})
+	}
+
+	section := &Section{Name: strings.TrimSuffix(filepath.Base(path), ".txt")}
+	for i, p1 := range pairs {
+		for j, p2 := range pairs {
+			if i == j {
+				continue
+			}
+			section.Questions = append(section.Questions, &Question{
+				Query:   Query{A: p1.Word, B: p1.Related[0], C: p2.Word},
+				Answers: p2.Related,
+			})
+		}
+	}
+	return section, nil
+}
+
+// A SectionResult summarizes the performance on a set of
+// questions.
+type SectionResult struct {
+	Name string
+
+	// Total is the number of questions.
+	Total int
+
+	// Answered is the number of questions for which every
+	// query word and at least one accepted answer were in
+	// the embedding.
+	// Other questions are skipped.
+	Answered int
+
+	// Correct is the number of answered questions for which
+	// the best answer was accepted.
+	Correct int
+}
+
+// Accuracy returns the fraction of answered questions
+// which were answered correctly.
+func (s *SectionResult) Accuracy() float64 {
+	if s.Answered == 0 {
+		return 0
+	}
+	return float64(s.Correct) / float64(s.Answered)
+}
+
+// Coverage returns the fraction of questions which were
+// answered.
+func (s *SectionResult) Coverage() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return float64(s.Answered) / float64(s.Total)
+}
+
+func (s *SectionResult) String() string {
+	return fmt.Sprintf("%s: accuracy=%.4f (%d/%d) coverage=%.4f (%d/%d)", s.Name,
+		s.Accuracy(), s.Correct, s.Answered, s.Coverage(), s.Answered, s.Total)
+}
+
+// A Result summarizes the performance on a benchmark.
+type Result struct {
+	Sections []*SectionResult
+	Overall  *SectionResult
+}
+
+// Evaluate runs the questions from a benchmark through a
+// Solver.
+func Evaluate(s *Solver, sections []*Section) *Result {
+	res := &Result{Overall: &SectionResult{Name: "overall"}}
+	for _, section := range sections {
+		sectionRes := &SectionResult{Name: section.Name, Total: len(section.Questions)}
+		var queries []Query
+		var answers [][]string
+		for _, q := range section.Questions {
+			if s.canAnswer(q) {
+				queries = append(queries, q.Query)
+				answers = append(answers, q.Answers)
+			}
+		}
+		sectionRes.Answered = len(queries)
+		for i := 0; i < len(queries); i += evalBatchSize {
+			batch := queries[i:essentials.MinInt(len(queries), i+evalBatchSize)]
+			results, err := s.SolveBatch(batch, 1)
+			if err != nil {
+				// All the query words were checked above.
+				panic(err)
+			}
+			for j, result := range results {
+				if len(result) > 0 && containsToken(answers[i+j], result[0].Token) {
+					sectionRes.Correct++
+				}
+			}
+		}
+		res.Sections = append(res.Sections, sectionRes)
+		res.Overall.Total += sectionRes.Total
+		res.Overall.Answered += sectionRes.Answered
+		res.Overall.Correct += sectionRes.Correct
+	}
+	return res
+}
+
+func (s *Solver) canAnswer(q *Question) bool {
+	for _, word := range []string{q.Query.A, q.Query.B, q.Query.C} {
+		if !s.Contains(word) {
+			return false
+		}
+	}
+	for _, answer := range q.Answers {
+		if s.Contains(answer) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsToken(tokens []string, token string) bool {
+	for _, t := range tokens {
+		if t == token {
+			return true
+		}
+	}
+	return false
+}
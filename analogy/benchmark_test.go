@@ -0,0 +1,122 @@
+package analogy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEvaluateQuestions(t *testing.T) {
+	data := ": royalty\n" +
+		"man king woman queen\n" +
+		"woman queen man king\n" +
+		"man king woman pear\n" +
+		"\n" +
+		": fruit\n" +
+		"man king dog cat\n"
+	sections, err := ReadQuestions(strings.NewReader(data), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sections) != 2 || sections[0].Name != "royalty" || len(sections[0].Questions) != 3 {
+		t.Fatalf("unexpected sections: %v", sections)
+	}
+
+	res := Evaluate(NewSolver(testEmbedding(), CosAdd), sections)
+	expected := []SectionResult{
+		{Name: "royalty", Total: 3, Answered: 3, Correct: 2},
+		{Name: "fruit", Total: 1},
+	}
+	for i, x := range expected {
+		if *res.Sections[i] != x {
+			t.Errorf("section %d: expected %v but got %v", i, x, res.Sections[i])
+		}
+	}
+	overall := SectionResult{Name: "overall", Total: 4, Answered: 3, Correct: 2}
+	if *res.Overall != overall {
+		t.Errorf("expected %v but got %v", overall, res.Overall)
+	}
+	if res.Overall.Coverage() != 0.75 {
+		t.Errorf("unexpected coverage: %f", res.Overall.Coverage())
+	}
+}
+
+func TestReadQuestionsLowercase(t *testing.T) {
+	data := ": capital-common-countries\n" +
+		"Athens Greece Baghdad Iraq\n"
+	for _, lowercase := range []bool{false, true} {
+		sections, err := ReadQuestions(strings.NewReader(data), lowercase)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := &Question{
+			Query:   Query{A: "Athens", B: "Greece", C: "Baghdad"},
+			Answers: []string{"Iraq"},
+		}
+		if lowercase {
+			expected = &Question{
+				Query:   Query{A: "athens", B: "greece", C: "baghdad"},
+				Answers: []string{"iraq"},
+			}
+		}
+		if actual := sections[0].Questions[0]; !reflect.DeepEqual(actual, expected) {
+			t.Errorf("lowercase=%v: expected %v but got %v", lowercase, expected, actual)
+		}
+	}
+}
+
+func TestReadQuestionsErrors(t *testing.T) {
+	for _, data := range []string{"man king woman queen\n", ": a\nman king woman\n"} {
+		if _, err := ReadQuestions(strings.NewReader(data), false); err == nil {
+			t.Errorf("expected error for %q", data)
+		}
+	}
+}
+
+func TestLoadBATS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	subdir := filepath.Join(dir, "1_Encyclopedic_semantics")
+	if err := os.Mkdir(subdir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		filepath.Join(subdir, "E01 [royalty].txt"): "man\tking/emperor\nwoman\tqueen\n",
+		filepath.Join(dir, "README.txt"):           "not a section",
+	}
+	for path, data := range files {
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sections, err := LoadBATS(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sections) != 1 || sections[0].Name != "E01 [royalty]" {
+		t.Fatalf("unexpected sections: %v", sections)
+	}
+	questions := sections[0].Questions
+	if len(questions) != 2 {
+		t.Fatalf("expected 2 questions but got %d", len(questions))
+	}
+	if questions[0].Query != (Query{A: "man", B: "king", C: "woman"}) ||
+		questions[1].Query != (Query{A: "woman", B: "queen", C: "man"}) {
+		t.Errorf("unexpected queries: %v, %v", questions[0].Query, questions[1].Query)
+	}
+	if len(questions[1].Answers) != 2 || questions[1].Answers[1] != "emperor" {
+		t.Errorf("unexpected answers: %v", questions[1].Answers)
+	}
+
+	res := Evaluate(NewSolver(testEmbedding(), CosMul), sections)
+	if res.Overall.Answered != 2 || res.Overall.Correct != 2 {
+		t.Errorf("unexpected result: %v", res.Overall)
+	}
+}
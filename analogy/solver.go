@@ -0,0 +1,220 @@
+// Package analogy solves and evaluates word analogies of
+// the form "a is to b as c is to ?".
+package analogy
+
+import (
+	"errors"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/wordembed"
+)
+
+// Method determines how candidate answers are scored.
+type Method int
+
+const (
+	// CosAdd is the 3CosAdd method from Mikolov et al.,
+	// which scores a candidate x as
+	//
+	//     cos(x, b) - cos(x, a) + cos(x, c)
+	//
+	CosAdd Method = iota
+
+	// CosMul is the 3CosMul method from Levy and Goldberg,
+	// which scores a candidate x as
+	//
+	//     cos(x, b) * cos(x, c) / (cos(x, a) + epsilon)
+	//
+	// where each similarity is shifted to the range [0, 1].
+	CosMul
+)
+
+// cosMulEpsilon prevents division by zero in CosMul.
+const cosMulEpsilon = 1e-3
+
+// A Query is an analogy question "A is to B as C is to ?".
+type Query struct {
+	A string
+	B string
+	C string
+}
+
+// An Answer is a candidate answer to a Query.
+type Answer struct {
+	Token string
+	Score float64
+}
+
+// A Solver answers analogy queries using an embedding.
+type Solver struct {
+	Embedding wordembed.Embedding
+	Method    Method
+
+	// vocab maps every token in the embedding to its ID.
+	vocab map[string]int
+
+	// candidates lists the tokens which may be returned
+	// as answers, in the same order as the rows in
+	// searcher.
+	candidates []string
+	searcher   *wordembed.CosineSearcher
+}
+
+// NewSolver creates a Solver which may return any token
+// in the embedding as an answer.
+func NewSolver(e wordembed.Embedding, m Method) *Solver {
+	res := newSolver(e, m)
+	res.setCandidates(wordembed.EmbeddingTokens(e))
+	return res
+}
+
+// NewFrequentSolver creates a Solver which only returns
+// the n most frequent tokens as answers.
+//
+// Query words do not need to be frequent, but they must
+// be in the embedding.
+// Restricting the candidates is common practice when
+// evaluating analogies, since rare words are noisy and
+// make the search slower.
+func NewFrequentSolver(e wordembed.Embedding, m Method, counts wordembed.TokenCounts,
+	n int) *Solver {
+	res := newSolver(e, m)
+	var candidates []string
+	for _, token := range counts.MostCommon(n) {
+		if res.Contains(token) {
+			candidates = append(candidates, token)
+		}
+	}
+	res.setCandidates(candidates)
+	return res
+}
+
+func newSolver(e wordembed.Embedding, m Method) *Solver {
+	vocab := map[string]int{}
+	for id, token := range wordembed.EmbeddingTokens(e) {
+		vocab[token] = id
+	}
+	return &Solver{Embedding: e, Method: m, vocab: vocab}
+}
+
+func (s *Solver) setCandidates(candidates []string) {
+	s.candidates = candidates
+	var rows []anyvec.Vector
+	for _, token := range candidates {
+		rows = append(rows, s.Embedding.EmbedID(s.vocab[token]))
+	}
+	if len(rows) > 0 {
+		s.searcher = wordembed.NewCosineSearcher(&anyvec.Matrix{
+			Data: rows[0].Creator().Concat(rows...),
+			Rows: len(rows),
+			Cols: s.Embedding.Dim(),
+		})
+	}
+}
+
+// Contains checks if a token can be used in a query.
+func (s *Solver) Contains(token string) bool {
+	_, ok := s.vocab[token]
+	return ok
+}
+
+// NumCandidates returns the number of tokens which may be
+// returned as answers.
+func (s *Solver) NumCandidates() int {
+	return len(s.candidates)
+}
+
+// Solve finds the n best answers to a query, sorted from
+// best to worst.
+//
+// The query words themselves are never returned.
+// An error is returned if a query word is not in the
+// embedding.
+func (s *Solver) Solve(q Query, n int) ([]Answer, error) {
+	res, err := s.SolveBatch([]Query{q}, n)
+	if err != nil {
+		return nil, err
+	}
+	return res[0], nil
+}
+
+// SolveBatch is like Solve, but it answers many queries
+// using a single matrix product.
+//
+// The memory usage is proportional to the number of
+// queries times the number of candidates, so very large
+// batches should be split up.
+func (s *Solver) SolveBatch(queries []Query, n int) ([][]Answer, error) {
+	res := make([][]Answer, len(queries))
+	if len(queries) == 0 || s.searcher == nil {
+		return res, nil
+	}
+
+	var vecs []anyvec.Vector
+	for _, q := range queries {
+		for _, token := range []string{q.A, q.B, q.C} {
+			id, ok := s.vocab[token]
+			if !ok {
+				return nil, errors.New("solve analogy: unknown token: " + token)
+			}
+			vecs = append(vecs, s.Embedding.EmbedID(id))
+		}
+	}
+	sims := s.searcher.BatchSimilarities(&anyvec.Matrix{
+		Data: vecs[0].Creator().Concat(vecs...),
+		Rows: len(vecs),
+		Cols: s.Embedding.Dim(),
+	})
+
+	for i, q := range queries {
+		var simVecs [3]anyvec.Vector
+		for j := range simVecs {
+			row := i*3 + j
+			simVecs[j] = sims.Data.Slice(row*sims.Cols, (row+1)*sims.Cols)
+		}
+		scores := s.scores(simVecs[0], simVecs[1], simVecs[2])
+
+		// Request extra answers in case the query words are
+		// among the best candidates.
+		ids, values := wordembed.TopK(scores, n+3)
+		for j, id := range ids {
+			if len(res[i]) == n {
+				break
+			}
+			token := s.candidates[id]
+			if token == q.A || token == q.B || token == q.C {
+				continue
+			}
+			res[i] = append(res[i], Answer{
+				Token: token,
+				Score: wordembed.NumericFloat64(values[j]),
+			})
+		}
+	}
+	return res, nil
+}
+
+func (s *Solver) scores(simA, simB, simC anyvec.Vector) anyvec.Vector {
+	c := simA.Creator()
+	switch s.Method {
+	case CosAdd:
+		res := simB.Copy()
+		res.Sub(simA)
+		res.Add(simC)
+		return res
+	case CosMul:
+		shifted := make([]anyvec.Vector, 3)
+		for i, v := range []anyvec.Vector{simA, simB, simC} {
+			shifted[i] = v.Copy()
+			shifted[i].AddScalar(c.MakeNumeric(1))
+			shifted[i].Scale(c.MakeNumeric(0.5))
+		}
+		res := shifted[1]
+		res.Mul(shifted[2])
+		shifted[0].AddScalar(c.MakeNumeric(cosMulEpsilon))
+		res.Div(shifted[0])
+		return res
+	default:
+		panic("unknown analogy method")
+	}
+}
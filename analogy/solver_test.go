@@ -0,0 +1,100 @@
+package analogy
+
+import (
+	"testing"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvec32"
+	"github.com/unixpickle/wordembed"
+	"github.com/unixpickle/wordembed/glove"
+)
+
+func TestSolver(t *testing.T) {
+	e := testEmbedding()
+	for _, method := range []Method{CosAdd, CosMul} {
+		solver := NewSolver(e, method)
+		answers, err := solver.Solve(Query{A: "man", B: "king", C: "woman"}, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(answers) != 3 {
+			t.Fatalf("method %d: expected 3 answers but got %d", method, len(answers))
+		}
+		if answers[0].Token != "queen" {
+			t.Errorf("method %d: expected queen but got %v", method, answers)
+		}
+		for i, answer := range answers {
+			if answer.Token == "man" || answer.Token == "king" || answer.Token == "woman" {
+				t.Errorf("method %d: query word in answers: %v", method, answers)
+			}
+			if i > 0 && answer.Score > answers[i-1].Score {
+				t.Errorf("method %d: answers not sorted: %v", method, answers)
+			}
+		}
+	}
+}
+
+func TestSolverBatch(t *testing.T) {
+	solver := NewSolver(testEmbedding(), CosAdd)
+	queries := []Query{
+		{A: "man", B: "king", C: "woman"},
+		{A: "king", B: "man", C: "queen"},
+	}
+	answers, err := solver.SolveBatch(queries, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answers[0][0].Token != "queen" || answers[1][0].Token != "woman" {
+		t.Errorf("unexpected answers: %v", answers)
+	}
+	if _, err := solver.SolveBatch([]Query{{A: "man", B: "king", C: "foo"}}, 1); err == nil {
+		t.Error("expected error for unknown token")
+	}
+}
+
+func TestFrequentSolver(t *testing.T) {
+	counts := wordembed.TokenCounts{
+		"man":   10,
+		"woman": 10,
+		"king":  5,
+		"apple": 20,
+		"pear":  20,
+		"queen": 1,
+	}
+	solver := NewFrequentSolver(testEmbedding(), CosAdd, counts, 5)
+	if solver.NumCandidates() != 5 {
+		t.Fatalf("expected 5 candidates but got %d", solver.NumCandidates())
+	}
+	answers, err := solver.Solve(Query{A: "man", B: "king", C: "woman"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(answers) != 2 {
+		t.Fatalf("expected 2 answers but got %v", answers)
+	}
+	for _, answer := range answers {
+		if answer.Token != "apple" && answer.Token != "pear" {
+			t.Errorf("unexpected answer: %v", answer)
+		}
+	}
+}
+
+func testEmbedding() *glove.Embedding {
+	tokens := wordembed.TokenSet{"apple", "king", "man", "pear", "queen", "woman"}
+	return &glove.Embedding{
+		Tokens: tokens,
+		Vectors: &anyvec.Matrix{
+			Data: anyvec32.MakeVectorData([]float32{
+				0, 0, 1, 0.1,
+				1, 1, 0, 0,
+				1, 0, 0, 0,
+				0, 0, 1, -0.1,
+				1, 1, 0, 0.9,
+				1, 0, 0, 0.9,
+				0, 0, 0, 0,
+			}),
+			Rows: tokens.NumIDs(),
+			Cols: 4,
+		},
+	}
+}
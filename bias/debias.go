@@ -0,0 +1,235 @@
+package bias
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/essentials"
+	"github.com/unixpickle/wordembed"
+	"github.com/unixpickle/wordembed/glove"
+)
+
+const powerIterations = 100
+
+// A HardDebias removes a bias direction from an
+// embedding, as described in Bolukbasi et al., "Man is to
+// Computer Programmer as Woman is to Homemaker? Debiasing
+// Word Embeddings".
+//
+// The bias direction is the first principal component of
+// the differences between the words in each definitional
+// pair and the pair's mean.
+// Neutral words are then projected onto the subspace
+// orthogonal to the bias direction ("neutralize"), and
+// each equalize pair is moved so that its words are
+// symmetric about that subspace ("equalize").
+//
+// As in the original method, every vector is normalized
+// to unit length.
+type HardDebias struct {
+	// Definitional lists pairs which define the bias, such
+	// as {"she", "he"} and {"woman", "man"}.
+	Definitional [][2]string
+
+	// Equalize lists pairs which should differ only in the
+	// bias direction, such as {"grandmother",
+	// "grandfather"}.
+	Equalize [][2]string
+
+	// Neutral lists the words to neutralize.
+	//
+	// If this is nil, every word which is not in Specific,
+	// Definitional, or Equalize is neutralized.
+	Neutral []string
+
+	// Specific lists words which are inherently related to
+	// the bias, such as "mother".
+	// It is only used when Neutral is nil.
+	Specific []string
+}
+
+// Direction computes the unit-length bias direction for
+// an embedding.
+//
+// The sign is chosen so that the first word of the first
+// usable definitional pair has a larger projection than
+// the second word.
+// Pairs with a word which is not in the embedding are
+// skipped.
+func (h *HardDebias) Direction(e wordembed.Embedding) (anyvec.Vector, error) {
+	vocab := vocabSet(e)
+	direction, err := h.direction(e, vocab)
+	if err != nil {
+		return nil, essentials.AddCtx("bias direction", err)
+	}
+	c := e.EmbedID(0).Creator()
+	return c.MakeVectorData(c.MakeNumericList(direction)), nil
+}
+
+// Debias produces a debiased copy of an embedding.
+//
+// Words and pairs which are not in the embedding are
+// skipped.
+// The vector for the unknown token is copied without
+// modification.
+func (h *HardDebias) Debias(e wordembed.Embedding) (*glove.Embedding, error) {
+	vocab := vocabSet(e)
+	direction, err := h.direction(e, vocab)
+	if err != nil {
+		return nil, essentials.AddCtx("debias", err)
+	}
+
+	tokens := wordembed.TokenSet(append([]string{}, wordembed.EmbeddingTokens(e)...))
+	sort.Strings(tokens)
+	vecs := make([][]float64, tokens.NumIDs())
+	for id, token := range tokens {
+		vecs[id] = unitVector(e, token)
+	}
+	unknown := wordembed.NumericListFloat64(e.EmbedID(len(tokens)).Data())
+	vecs[len(tokens)] = append([]float64{}, unknown...)
+
+	for _, word := range h.neutralWords(tokens) {
+		if id := tokens.ID(word); id != len(tokens) {
+			vec := vecs[id]
+			removeComponent(vec, direction)
+			vecs[id] = wordembed.NormalizeFloat64(vec)
+		}
+	}
+
+	for _, pair := range h.Equalize {
+		id1, id2 := tokens.ID(pair[0]), tokens.ID(pair[1])
+		if id1 == len(tokens) || id2 == len(tokens) {
+			continue
+		}
+		v1, v2 := vecs[id1], vecs[id2]
+		mean := make([]float64, len(v1))
+		for i, x := range v1 {
+			mean[i] = (x + v2[i]) / 2
+		}
+		removeComponent(mean, direction)
+		z := math.Sqrt(math.Max(0, 1-wordembed.DotFloat64(mean, mean)))
+		if wordembed.DotFloat64(v1, direction) < wordembed.DotFloat64(v2, direction) {
+			z = -z
+		}
+		for i, m := range mean {
+			v1[i] = m + z*direction[i]
+			v2[i] = m - z*direction[i]
+		}
+	}
+
+	c := e.EmbedID(0).Creator()
+	var data []float64
+	for _, vec := range vecs {
+		data = append(data, vec...)
+	}
+	return &glove.Embedding{
+		Tokens: tokens,
+		Vectors: &anyvec.Matrix{
+			Data: c.MakeVectorData(c.MakeNumericList(data)),
+			Rows: len(vecs),
+			Cols: e.Dim(),
+		},
+	}, nil
+}
+
+func (h *HardDebias) direction(e wordembed.Embedding, vocab map[string]bool) ([]float64,
+	error) {
+	dim := e.Dim()
+	covariance := make([]float64, dim*dim)
+	var first []float64
+	for _, pair := range h.Definitional {
+		if !vocab[pair[0]] || !vocab[pair[1]] {
+			continue
+		}
+		v1 := unitVector(e, pair[0])
+		v2 := unitVector(e, pair[1])
+		diff := make([]float64, dim)
+		for i, x := range v1 {
+			diff[i] = (x - v2[i]) / 2
+		}
+		if first == nil {
+			first = diff
+		}
+		// Both centered vectors are +/- diff, so together they
+		// contribute 2*diff*diff^T.
+		for i, x := range diff {
+			for j, y := range diff {
+				covariance[i*dim+j] += 2 * x * y
+			}
+		}
+	}
+	if first == nil {
+		return nil, errors.New("no definitional pairs in vocabulary")
+	}
+
+	vec := make([]float64, dim)
+	for i := range vec {
+		vec[i] = rand.NormFloat64()
+	}
+	vec = wordembed.NormalizeFloat64(vec)
+	next := make([]float64, dim)
+	for iter := 0; iter < powerIterations; iter++ {
+		for i := range next {
+			next[i] = wordembed.DotFloat64(covariance[i*dim:(i+1)*dim], vec)
+		}
+		if wordembed.DotFloat64(next, next) == 0 {
+			return nil, errors.New("definitional pairs have no variance")
+		}
+		vec, next = wordembed.NormalizeFloat64(next), vec
+	}
+	if wordembed.DotFloat64(vec, first) < 0 {
+		for i := range vec {
+			vec[i] = -vec[i]
+		}
+	}
+	return vec, nil
+}
+
+func (h *HardDebias) neutralWords(tokens []string) []string {
+	if h.Neutral != nil {
+		return h.Neutral
+	}
+	excluded := map[string]bool{}
+	for _, word := range h.Specific {
+		excluded[word] = true
+	}
+	for _, pairs := range [][][2]string{h.Definitional, h.Equalize} {
+		for _, pair := range pairs {
+			excluded[pair[0]] = true
+			excluded[pair[1]] = true
+		}
+	}
+	var res []string
+	for _, token := range tokens {
+		if !excluded[token] {
+			res = append(res, token)
+		}
+	}
+	return res
+}
+
+// removeComponent removes the projection of vec onto a
+// unit vector in place.
+func removeComponent(vec, direction []float64) {
+	proj := wordembed.DotFloat64(vec, direction)
+	for i, x := range direction {
+		vec[i] -= proj * x
+	}
+}
+
+// unitVector looks up the vector for a token and scales it
+// to unit length.
+func unitVector(e wordembed.Embedding, token string) []float64 {
+	return wordembed.NormalizeFloat64(wordembed.NumericListFloat64(e.Embed(token).Data()))
+}
+
+func vocabSet(e wordembed.Embedding) map[string]bool {
+	res := map[string]bool{}
+	for _, token := range wordembed.EmbeddingTokens(e) {
+		res[token] = true
+	}
+	return res
+}
@@ -0,0 +1,103 @@
+package bias
+
+import (
+	"math"
+	"testing"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvec32"
+	"github.com/unixpickle/wordembed"
+	"github.com/unixpickle/wordembed/glove"
+)
+
+func TestHardDebias(t *testing.T) {
+	e := &glove.Embedding{
+		Tokens: wordembed.TokenSet{"doctor", "grandfather", "grandmother", "he", "nurse",
+			"she"},
+		Vectors: &anyvec.Matrix{
+			Data: anyvec32.MakeVectorData([]float32{
+				0.5, 0, 1,
+				0.8, 0.5, 0.1,
+				-0.6, 0.4, 0.3,
+				1, 0.2, 0,
+				-0.4, 1, 0.2,
+				-1, 0.2, 0,
+				7, 8, 9,
+			}),
+			Rows: 7,
+			Cols: 3,
+		},
+	}
+	debias := &HardDebias{
+		Definitional: [][2]string{{"he", "she"}, {"missing", "she"}},
+		Equalize:     [][2]string{{"grandfather", "grandmother"}},
+	}
+
+	direction, err := debias.Direction(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := wordembed.NumericListFloat64(direction.Data())
+	assertVector(t, "direction", actual, []float64{1, 0, 0})
+
+	res, err := debias.Debias(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vec := func(token string) []float64 {
+		return wordembed.NumericListFloat64(res.Embed(token).Data())
+	}
+
+	// Neutral words lose their bias component.
+	assertVector(t, "doctor", vec("doctor"), wordembed.NormalizeFloat64([]float64{0, 0, 1}))
+	assertVector(t, "nurse", vec("nurse"), wordembed.NormalizeFloat64([]float64{0, 1, 0.2}))
+
+	// Equalized words are symmetric about the neutral
+	// subspace.
+	father, mother := vec("grandfather"), vec("grandmother")
+	v1 := wordembed.NormalizeFloat64([]float64{0.8, 0.5, 0.1})
+	v2 := wordembed.NormalizeFloat64([]float64{-0.6, 0.4, 0.3})
+	mean := []float64{0, (v1[1] + v2[1]) / 2, (v1[2] + v2[2]) / 2}
+	z := math.Sqrt(1 - wordembed.DotFloat64(mean, mean))
+	assertVector(t, "grandfather", father, []float64{z, mean[1], mean[2]})
+	assertVector(t, "grandmother", mother, []float64{-z, mean[1], mean[2]})
+
+	// Definitional words are only normalized.
+	assertVector(t, "he", vec("he"), wordembed.NormalizeFloat64([]float64{1, 0.2, 0}))
+	assertVector(t, "unknown", vec("unknown"), []float64{7, 8, 9})
+
+	// Neutralizing removes the association measured by a
+	// WEAT.
+	weat := &WEAT{
+		X: []string{"doctor"},
+		Y: []string{"nurse"},
+		A: []string{"he"},
+		B: []string{"she"},
+	}
+	before, err := weat.Run(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := weat.Run(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(before.Statistic) < 0.1 || math.Abs(after.Statistic) > 1e-5 {
+		t.Errorf("unexpected statistics: before %f, after %f", before.Statistic,
+			after.Statistic)
+	}
+
+	debias.Definitional = [][2]string{{"missing", "she"}}
+	if _, err := debias.Debias(e); err == nil {
+		t.Error("expected error without definitional pairs")
+	}
+}
+
+func assertVector(t *testing.T, name string, actual, expected []float64) {
+	for i, x := range expected {
+		if math.Abs(x-actual[i]) > 1e-5 {
+			t.Errorf("%s: expected %v but got %v", name, expected, actual)
+			return
+		}
+	}
+}
@@ -0,0 +1,212 @@
+// Package bias measures and removes biases in word
+// embeddings.
+package bias
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+
+	"github.com/unixpickle/essentials"
+	"github.com/unixpickle/wordembed"
+)
+
+const defaultPermutations = 10000
+
+// A WEAT is a Word Embedding Association Test, as
+// described in Caliskan et al., "Semantics derived
+// automatically from language corpora contain human-like
+// biases".
+//
+// The test measures whether the target words in X are
+// more associated with the attribute words in A (relative
+// to B) than the target words in Y are.
+type WEAT struct {
+	// X and Y are the two sets of target words, such as
+	// career and family words.
+	X []string
+	Y []string
+
+	// A and B are the two sets of attribute words, such as
+	// male and female names.
+	A []string
+	B []string
+
+	// Permutations is the maximum number of partitions of
+	// X and Y to evaluate for the p-value.
+	// If there are fewer possible partitions, the exact
+	// p-value is computed.
+	// Otherwise, this many random partitions are sampled.
+	//
+	// If this is 0, 10000 is used.
+	Permutations int
+}
+
+// WEATResult stores the results of a WEAT.
+type WEATResult struct {
+	// Statistic is the test statistic, the difference
+	// between the total association of X and the total
+	// association of Y.
+	Statistic float64
+
+	// EffectSize is the difference between the mean
+	// associations of X and Y, divided by the sample
+	// standard deviation of the associations of all the
+	// target words.
+	EffectSize float64
+
+	// PValue is the one-sided p-value from the permutation
+	// test, i.e. the probability that a random partition of
+	// the target words has a statistic at least as large.
+	PValue float64
+
+	// Exact is true if PValue was computed from every
+	// partition rather than a random sample.
+	Exact bool
+
+	// Missing lists the words which were skipped because
+	// they are not in the embedding.
+	Missing []string
+}
+
+// String summarizes the result.
+func (w *WEATResult) String() string {
+	return fmt.Sprintf("statistic=%.4f effect_size=%.4f p=%.4g", w.Statistic,
+		w.EffectSize, w.PValue)
+}
+
+// Run performs the test on an embedding.
+//
+// Words which are not in the embedding are skipped.
+// An error is returned if this leaves any of the word sets
+// empty.
+func (w *WEAT) Run(e wordembed.Embedding) (result *WEATResult, err error) {
+	defer essentials.AddCtxTo("run WEAT", &err)
+	vocab := vocabSet(e)
+	res := &WEATResult{}
+	var sets [4][][]float64
+	for i, words := range [][]string{w.X, w.Y, w.A, w.B} {
+		for _, word := range words {
+			if vocab[word] {
+				sets[i] = append(sets[i], unitVector(e, word))
+			} else {
+				res.Missing = append(res.Missing, word)
+			}
+		}
+		if len(sets[i]) == 0 {
+			return nil, errors.New("empty word set")
+		}
+	}
+	x, y, a, b := sets[0], sets[1], sets[2], sets[3]
+
+	// Compute the association s(w, A, B) of every target.
+	associations := make([]float64, 0, len(x)+len(y))
+	for _, vec := range append(append([][]float64{}, x...), y...) {
+		associations = append(associations, meanSimilarity(vec, a)-meanSimilarity(vec, b))
+	}
+	var total, xTotal float64
+	for i, assoc := range associations {
+		total += assoc
+		if i < len(x) {
+			xTotal += assoc
+		}
+	}
+	res.Statistic = 2*xTotal - total
+
+	var variance float64
+	mean := total / float64(len(associations))
+	for _, assoc := range associations {
+		variance += (assoc - mean) * (assoc - mean)
+	}
+	if len(associations) > 1 {
+		variance /= float64(len(associations) - 1)
+	}
+	xMean := xTotal / float64(len(x))
+	yMean := (total - xTotal) / float64(len(y))
+	if variance > 0 {
+		res.EffectSize = (xMean - yMean) / math.Sqrt(variance)
+	}
+
+	res.PValue, res.Exact = w.pValue(associations, len(x), res.Statistic)
+	return res, nil
+}
+
+// pValue runs the permutation test, where the first
+// numX associations belong to X.
+func (w *WEAT) pValue(associations []float64, numX int, statistic float64) (float64,
+	bool) {
+	permutations := w.Permutations
+	if permutations == 0 {
+		permutations = defaultPermutations
+	}
+	var total float64
+	for _, assoc := range associations {
+		total += assoc
+	}
+	partitionStat := func(indices []int) float64 {
+		var sum float64
+		for _, i := range indices {
+			sum += associations[i]
+		}
+		return 2*sum - total
+	}
+
+	// Compare with a tolerance, since the observed partition
+	// may be summed in a different order.
+	epsilon := 1e-12 * math.Max(1, math.Abs(statistic))
+
+	n := len(associations)
+	numPartitions := new(big.Int).Binomial(int64(n), int64(numX))
+	if numPartitions.IsInt64() && numPartitions.Int64() <= int64(permutations) {
+		var count, checked int
+		indices := make([]int, numX)
+		for i := range indices {
+			indices[i] = i
+		}
+		for {
+			checked++
+			if partitionStat(indices) >= statistic-epsilon {
+				count++
+			}
+			if !nextCombination(indices, n) {
+				break
+			}
+		}
+		return float64(count) / float64(checked), true
+	}
+
+	var count int
+	for i := 0; i < permutations; i++ {
+		if partitionStat(rand.Perm(n)[:numX]) >= statistic-epsilon {
+			count++
+		}
+	}
+	return float64(count+1) / float64(permutations+1), false
+}
+
+// nextCombination advances a sorted k-subset of [0, n) to
+// the next one in lexicographic order.
+// It returns false if there is no next subset.
+func nextCombination(indices []int, n int) bool {
+	k := len(indices)
+	for i := k - 1; i >= 0; i-- {
+		if indices[i] < n-k+i {
+			indices[i]++
+			for j := i + 1; j < k; j++ {
+				indices[j] = indices[j-1] + 1
+			}
+			return true
+		}
+	}
+	return false
+}
+
+func meanSimilarity(vec []float64, others [][]float64) float64 {
+	var sum float64
+	for _, other := range others {
+		sum += wordembed.DotFloat64(vec, other)
+	}
+	return sum / float64(len(others))
+}
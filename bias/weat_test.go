@@ -0,0 +1,105 @@
+package bias
+
+import (
+	"math"
+	"testing"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvec32"
+	"github.com/unixpickle/wordembed"
+	"github.com/unixpickle/wordembed/glove"
+)
+
+func TestWEAT(t *testing.T) {
+	e := testEmbedding()
+	weat := &WEAT{
+		X: []string{"x1", "x2", "x3", "missing"},
+		Y: []string{"y1", "y2", "y3"},
+		A: []string{"a1", "a2"},
+		B: []string{"b1", "b2"},
+	}
+	res, err := weat.Run(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Exact {
+		t.Error("expected exact p-value")
+	}
+	// The observed partition is the most extreme of the 20
+	// possible partitions.
+	if math.Abs(res.PValue-1.0/20) > 1e-8 {
+		t.Errorf("expected p-value 0.05 but got %f", res.PValue)
+	}
+	if res.Statistic <= 0 || res.EffectSize <= 1 {
+		t.Errorf("unexpected result: %s", res)
+	}
+	if len(res.Missing) != 1 || res.Missing[0] != "missing" {
+		t.Errorf("unexpected missing words: %v", res.Missing)
+	}
+
+	weat.X, weat.Y = weat.Y, weat.X
+	res, err = weat.Run(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.PValue != 1 || res.EffectSize >= -1 {
+		t.Errorf("unexpected result: %s", res)
+	}
+
+	weat.Permutations = 5
+	res, err = weat.Run(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Exact || res.PValue <= 0 || res.PValue > 1 {
+		t.Errorf("unexpected sampled result: %s (exact=%v)", res, res.Exact)
+	}
+
+	weat.A = []string{"missing"}
+	if _, err := weat.Run(e); err == nil {
+		t.Error("expected error for empty set")
+	}
+}
+
+func TestNextCombination(t *testing.T) {
+	indices := []int{0, 1}
+	var count int
+	for {
+		count++
+		if !nextCombination(indices, 5) {
+			break
+		}
+	}
+	if count != 10 {
+		t.Errorf("expected 10 combinations but got %d", count)
+	}
+}
+
+func testEmbedding() *glove.Embedding {
+	vecs := map[string][]float32{
+		"a1": {1, 0.1, 0},
+		"a2": {0.9, -0.1, 0.1},
+		"b1": {-1, 0.1, 0},
+		"b2": {-0.9, 0, -0.1},
+		"x1": {0.5, 1, 0},
+		"x2": {0.7, 0, 1},
+		"x3": {0.2, 0.3, 0.4},
+		"y1": {-0.5, 1, 0},
+		"y2": {-0.7, 0, 1},
+		"y3": {-0.3, 0.4, 0.3},
+	}
+	tokens := wordembed.TokenSet{"a1", "a2", "b1", "b2", "x1", "x2", "x3", "y1", "y2", "y3"}
+	var data []float32
+	for _, token := range tokens {
+		data = append(data, vecs[token]...)
+	}
+	data = append(data, 0, 0, 0)
+	return &glove.Embedding{
+		Tokens: tokens,
+		Vectors: &anyvec.Matrix{
+			Data: anyvec32.MakeVectorData(data),
+			Rows: tokens.NumIDs(),
+			Cols: 3,
+		},
+	}
+}
@@ -22,3 +22,20 @@ type Embedding interface {
 	// Token looks up the token for the token ID.
 	Token(id int) string
 }
+
+// EmbeddingTokens lists the tokens of an embedding.
+//
+// Token IDs are visited starting at 0, stopping at the
+// first ID with an empty token.
+// This matches the semantics of TokenSet, where the
+// unknown token is "".
+func EmbeddingTokens(e Embedding) []string {
+	var res []string
+	for id := 0; ; id++ {
+		token := e.Token(id)
+		if token == "" {
+			return res
+		}
+		res = append(res, token)
+	}
+}
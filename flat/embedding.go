@@ -0,0 +1,257 @@
+// Package flat implements a read-only on-disk format for
+// word embeddings which can be memory-mapped.
+//
+// A flat file is laid out as follows, with all integers
+// and floats stored in little-endian order:
+//
+//	magic       8 bytes, "WEFLAT01"
+//	numTokens   uint64
+//	dim         uint64
+//	rowsOffset  uint64
+//	offsets     (numTokens+1) uint64s
+//	tokens      the concatenated UTF-8 tokens
+//	padding     zeros up to rowsOffset
+//	rows        (numTokens+1)*dim float32s
+//
+// The tokens are sorted, and token i occupies the bytes
+// from offsets[i] to offsets[i+1], relative to the start
+// of the token data.
+// As with wordembed.TokenSet, row i is the vector for
+// token i, and the final row is the vector for unknown
+// tokens.
+package flat
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"unsafe"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvec32"
+	"github.com/unixpickle/essentials"
+	"github.com/unixpickle/wordembed"
+)
+
+const (
+	magic      = "WEFLAT01"
+	headerSize = len(magic) + 8*3
+)
+
+// An Embedding serves vectors directly from a flat file.
+//
+// Opening an Embedding does not read the vectors into the
+// heap, so multiple processes using the same file share
+// the same physical memory.
+type Embedding struct {
+	numTokens int
+	dim       int
+	offsets   []byte
+	tokens    []byte
+	rows      []float32
+
+	data  []byte
+	unmap func([]byte) error
+
+	normsOnce sync.Once
+	norms     []float32
+}
+
+// Open memory-maps a flat file.
+//
+// On platforms without mmap support, the file is read
+// into memory instead.
+//
+// The Embedding should be closed when it is no longer
+// needed.
+func Open(path string) (embedding *Embedding, err error) {
+	defer essentials.AddCtxTo("open flat embedding", &err)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	data, unmap, err := mapFile(f, int(info.Size()))
+	if err != nil {
+		return nil, err
+	}
+	res, err := newEmbedding(data)
+	if err != nil {
+		unmap(data)
+		return nil, err
+	}
+	res.unmap = unmap
+	return res, nil
+}
+
+func newEmbedding(data []byte) (*Embedding, error) {
+	if !hostLittleEndian() {
+		return nil, errors.New("big-endian hosts are not supported")
+	}
+	if len(data) < headerSize || string(data[:len(magic)]) != magic {
+		return nil, errors.New("invalid header")
+	}
+	header := data[len(magic):]
+	numTokens := binary.LittleEndian.Uint64(header)
+	dim := binary.LittleEndian.Uint64(header[8:])
+	rowsOffset := binary.LittleEndian.Uint64(header[16:])
+
+	size := uint64(len(data))
+	tokensOffset := uint64(headerSize) + 8*(numTokens+1)
+	if numTokens >= size || dim == 0 || dim >= size || tokensOffset > size ||
+		rowsOffset < tokensOffset || rowsOffset > size || rowsOffset%4 != 0 {
+		return nil, errors.New("invalid sizes")
+	}
+	rowBytes := size - rowsOffset
+	if rowBytes%(4*dim) != 0 || rowBytes/(4*dim) != numTokens+1 {
+		return nil, errors.New("invalid sizes")
+	}
+	res := &Embedding{
+		numTokens: int(numTokens),
+		dim:       int(dim),
+		offsets:   data[headerSize:tokensOffset],
+		tokens:    data[tokensOffset:rowsOffset],
+		rows:      float32Slice(data[rowsOffset:]),
+		data:      data,
+	}
+	var last uint64
+	for i := 0; i <= res.numTokens; i++ {
+		offset := res.offset(i)
+		if offset < last || offset > uint64(len(res.tokens)) {
+			return nil, errors.New("invalid token offsets")
+		}
+		last = offset
+	}
+	return res, nil
+}
+
+// Close releases the file's memory.
+//
+// The Embedding, and any rows returned by Row, may not be
+// used after Close is called.
+func (e *Embedding) Close() error {
+	if e.data == nil {
+		return nil
+	}
+	data := e.data
+	e.data, e.rows, e.offsets, e.tokens = nil, nil, nil, nil
+	return e.unmap(data)
+}
+
+// Dim returns the dimensionality of the embedding.
+func (e *Embedding) Dim() int {
+	return e.dim
+}
+
+// NumIDs returns the number of token IDs, including the
+// ID for unknown tokens.
+func (e *Embedding) NumIDs() int {
+	return e.numTokens + 1
+}
+
+// ID finds the ID of a token.
+//
+// If the token is not present, the unknown ID is returned.
+func (e *Embedding) ID(token string) int {
+	idx := sort.Search(e.numTokens, func(i int) bool {
+		return string(e.tokenBytes(i)) >= token
+	})
+	if idx < e.numTokens && string(e.tokenBytes(idx)) == token {
+		return idx
+	}
+	return e.numTokens
+}
+
+// Token returns the token for the token ID.
+//
+// If the ID corresponds to the unknown token, then "" is
+// returned.
+func (e *Embedding) Token(id int) string {
+	if id >= e.numTokens {
+		return ""
+	}
+	return string(e.tokenBytes(id))
+}
+
+// Row returns the vector for a token ID without copying
+// it.
+//
+// The result refers directly to the file's memory, so it
+// must not be modified.
+func (e *Embedding) Row(id int) []float32 {
+	return e.rows[id*e.dim : (id+1)*e.dim]
+}
+
+// Embed returns the embedding for the token.
+func (e *Embedding) Embed(token string) anyvec.Vector {
+	return e.EmbedID(e.ID(token))
+}
+
+// EmbedID returns the embedding for the token ID.
+//
+// The result is a float32 copy of the row, since callers
+// are free to modify it.
+// See Row for zero-copy access.
+func (e *Embedding) EmbedID(id int) anyvec.Vector {
+	return anyvec32.MakeVectorData(append([]float32{}, e.Row(id)...))
+}
+
+// Lookup finds the n closest token IDs to the given
+// vector, using cosine similarity.
+// For each ID, it also returns the similarity.
+//
+// The magnitude of every row is cached after the first
+// call.
+//
+// If n is greater than the number of IDs, then there will
+// be fewer than n results.
+func (e *Embedding) Lookup(vec anyvec.Vector, n int) ([]int, []anyvec.Numeric) {
+	if vec.Len() != e.dim {
+		panic("incorrect vector length")
+	}
+	e.normsOnce.Do(func() {
+		e.norms = make([]float32, e.NumIDs())
+		for id := range e.norms {
+			row := e.Row(id)
+			e.norms[id] = float32(math.Sqrt(float64(wordembed.DotFloat32(row, row))))
+		}
+	})
+	query := wordembed.NumericListFloat32(vec.Data())
+	queryNorm := math.Sqrt(float64(wordembed.DotFloat32(query, query)))
+	sims := make([]float64, len(e.norms))
+	for id, norm := range e.norms {
+		if norm != 0 && queryNorm != 0 {
+			dot := float64(wordembed.DotFloat32(query, e.Row(id)))
+			sims[id] = dot / (queryNorm * float64(norm))
+		}
+	}
+	c := vec.Creator()
+	return wordembed.TopK(c.MakeVectorData(c.MakeNumericList(sims)), n)
+}
+
+func (e *Embedding) offset(i int) uint64 {
+	return binary.LittleEndian.Uint64(e.offsets[i*8:])
+}
+
+func (e *Embedding) tokenBytes(id int) []byte {
+	return e.tokens[e.offset(id):e.offset(id+1)]
+}
+
+func float32Slice(data []byte) []float32 {
+	if len(data) == 0 {
+		return nil
+	}
+	return unsafe.Slice((*float32)(unsafe.Pointer(&data[0])), len(data)/4)
+}
+
+func hostLittleEndian() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}
@@ -0,0 +1,160 @@
+package flat
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvec32"
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/wordembed"
+	"github.com/unixpickle/wordembed/glove"
+	"github.com/unixpickle/wordembed/word2vec"
+)
+
+func TestEmbedding(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	source := testEmbedding()
+	path := filepath.Join(dir, "embedding.flat")
+	if err := WriteFile(path, source); err != nil {
+		t.Fatal(err)
+	}
+	e, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	if e.Dim() != source.Dim() || e.NumIDs() != source.Tokens.NumIDs() {
+		t.Fatalf("bad shape: dim %d, %d IDs", e.Dim(), e.NumIDs())
+	}
+	for id := 0; id < source.Tokens.NumIDs(); id++ {
+		token := source.Token(id)
+		if e.Token(id) != token {
+			t.Errorf("ID %d: expected %q but got %q", id, token, e.Token(id))
+		}
+		if id < len(source.Tokens) && e.ID(token) != id {
+			t.Errorf("token %q: expected ID %d but got %d", token, id, e.ID(token))
+		}
+		expected := source.EmbedID(id).Data()
+		if actual := e.EmbedID(id).Data(); !reflect.DeepEqual(actual, expected) {
+			t.Errorf("ID %d: expected %v but got %v", id, expected, actual)
+		}
+		if actual := e.Row(id); !reflect.DeepEqual(actual, expected) {
+			t.Errorf("ID %d: expected row %v but got %v", id, expected, actual)
+		}
+	}
+	if e.ID("missing") != len(source.Tokens) || e.ID("") != len(source.Tokens) {
+		t.Error("missing token should map to unknown ID")
+	}
+	expected := source.EmbedID(len(source.Tokens)).Data()
+	if actual := e.Embed("missing").Data(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v but got %v", expected, actual)
+	}
+
+	query := anyvec32.MakeVectorData([]float32{1, 0.5, -1})
+	expectedIDs, expectedSims := source.Lookup(query, 3)
+	actualIDs, actualSims := e.Lookup(query, 3)
+	if !reflect.DeepEqual(actualIDs, expectedIDs) {
+		t.Errorf("expected IDs %v but got %v", expectedIDs, actualIDs)
+	}
+	for i, sim := range expectedSims {
+		if math.Abs(float64(sim.(float32)-actualSims[i].(float32))) > 1e-5 {
+			t.Errorf("expected similarities %v but got %v", expectedSims, actualSims)
+			break
+		}
+	}
+}
+
+func TestConvertFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	source := testEmbedding()
+	w2v := &word2vec.Embed{
+		Matrix: anydiff.NewVar(source.Vectors.Data.Slice(0, len(source.Tokens)*3)),
+		Words:  source.Tokens,
+	}
+	for i, obj := range []serializer.Serializer{source, w2v} {
+		data, err := serializer.SerializeAny(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inPath := filepath.Join(dir, "embedding")
+		if err := ioutil.WriteFile(inPath, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		outPath := filepath.Join(dir, "embedding.flat")
+		if err := ConvertFile(inPath, outPath); err != nil {
+			t.Fatal(err)
+		}
+		e, err := Open(outPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := source.EmbedID(1).Data()
+		if actual := e.Embed("b").Data(); !reflect.DeepEqual(actual, expected) {
+			t.Errorf("case %d: expected %v but got %v", i, expected, actual)
+		}
+		if i == 1 {
+			if unknown := e.Row(len(source.Tokens)); !reflect.DeepEqual(unknown,
+				[]float32{0, 0, 0}) {
+				t.Errorf("expected zero unknown vector but got %v", unknown)
+			}
+		}
+		e.Close()
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, testEmbedding()); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if _, err := newEmbedding(data); err != nil {
+		t.Fatal(err)
+	}
+	for _, corrupt := range [][]byte{
+		nil,
+		data[:len(data)-1],
+		append([]byte("WEFLAT99"), data[8:]...),
+		append(append([]byte{}, data[:8]...), bytes.Repeat([]byte{0xff}, len(data)-8)...),
+	} {
+		if _, err := newEmbedding(corrupt); err == nil {
+			t.Error("expected error")
+		}
+	}
+}
+
+func testEmbedding() *glove.Embedding {
+	tokens := wordembed.TokenSet{"a", "b", "hello", "world"}
+	return &glove.Embedding{
+		Tokens: tokens,
+		Vectors: &anyvec.Matrix{
+			Data: anyvec32.MakeVectorData([]float32{
+				1, 0, 0,
+				0.5, 0.5, -1,
+				-1, 2, 3,
+				0, 0, 1,
+				2, 2, 2,
+			}),
+			Rows: 5,
+			Cols: 3,
+		},
+	}
+}
@@ -0,0 +1,122 @@
+package flat
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/unixpickle/essentials"
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/wordembed"
+	"github.com/unixpickle/wordembed/word2vec"
+)
+
+// Write encodes an embedding in the flat format.
+//
+// The tokens of the embedding are listed with
+// wordembed.EmbeddingTokens, and the vector for unknown
+// tokens is the embedding's vector for the first ID past
+// the tokens.
+func Write(w io.Writer, e wordembed.Embedding) (err error) {
+	defer essentials.AddCtxTo("write flat embedding", &err)
+
+	tokens := append([]string{}, wordembed.EmbeddingTokens(e)...)
+	sort.Strings(tokens)
+	var tokenBytes int
+	for _, token := range tokens {
+		tokenBytes += len(token)
+	}
+	tokensOffset := headerSize + 8*(len(tokens)+1)
+	rowsOffset := tokensOffset + tokenBytes
+	padding := (4 - rowsOffset%4) % 4
+	rowsOffset += padding
+
+	bw := bufio.NewWriter(w)
+	buf := make([]byte, 8)
+	writeUint64 := func(x uint64) {
+		binary.LittleEndian.PutUint64(buf, x)
+		bw.Write(buf)
+	}
+	bw.WriteString(magic)
+	writeUint64(uint64(len(tokens)))
+	writeUint64(uint64(e.Dim()))
+	writeUint64(uint64(rowsOffset))
+	var offset uint64
+	writeUint64(0)
+	for _, token := range tokens {
+		offset += uint64(len(token))
+		writeUint64(offset)
+	}
+	for _, token := range tokens {
+		bw.WriteString(token)
+	}
+	bw.Write(make([]byte, padding))
+
+	writeRow := func(vec []float32) error {
+		if len(vec) != e.Dim() {
+			return errors.New("inconsistent vector length")
+		}
+		for _, x := range vec {
+			binary.LittleEndian.PutUint32(buf, math.Float32bits(x))
+			bw.Write(buf[:4])
+		}
+		return nil
+	}
+	for _, token := range tokens {
+		row := wordembed.NumericListFloat32(e.Embed(token).Data())
+		if err := writeRow(row); err != nil {
+			return err
+		}
+	}
+	unknown := wordembed.NumericListFloat32(e.EmbedID(len(tokens)).Data())
+	if err := writeRow(unknown); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// WriteFile encodes an embedding to a flat file.
+func WriteFile(path string, e wordembed.Embedding) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return essentials.AddCtx("write flat embedding", err)
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil && closeErr != nil {
+			err = essentials.AddCtx("write flat embedding", closeErr)
+		}
+	}()
+	return Write(f, e)
+}
+
+// ConvertFile converts an embedding saved with the
+// serializer package into a flat file.
+//
+// The saved object may be any wordembed.Embedding which
+// implements serializer.Serializer, such as a
+// glove.Embedding, or a *word2vec.Embed.
+func ConvertFile(serializedPath, flatPath string) error {
+	data, err := ioutil.ReadFile(serializedPath)
+	if err != nil {
+		return essentials.AddCtx("convert to flat embedding", err)
+	}
+	var obj serializer.Serializer
+	if err := serializer.DeserializeAny(data, &obj); err != nil {
+		return essentials.AddCtx("convert to flat embedding", err)
+	}
+	var e wordembed.Embedding
+	switch obj := obj.(type) {
+	case *word2vec.Embed:
+		e = &word2vec.Embedding{Model: obj}
+	case wordembed.Embedding:
+		e = obj
+	default:
+		return errors.New("convert to flat embedding: not a wordembed.Embedding")
+	}
+	return WriteFile(flatPath, e)
+}
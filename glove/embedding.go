@@ -20,6 +20,10 @@ type Embedding struct {
 
 	// Vectors contains one row per token ID.
 	Vectors *anyvec.Matrix
+
+	// searcher is created lazily, and it is shared by
+	// copies of the Embedding.
+	searcher *wordembed.SearcherCache
 }
 
 // DeserializeEmbedding deserializes an Embedding.
@@ -47,6 +51,7 @@ func (e *Embedding) Normalize() {
 	normalizers := anyvec.SumCols(squares, e.Vectors.Rows)
 	anyvec.Pow(normalizers, c.MakeNumeric(-0.5))
 	anyvec.ScaleChunks(e.Vectors.Data, normalizers)
+	e.ClearCache()
 }
 
 // Embed returns the embedding for the token.
@@ -65,38 +70,33 @@ func (e *Embedding) EmbedID(id int) anyvec.Vector {
 //
 // If n is greater than the number of IDs, then there will
 // be fewer than n results.
+//
+// The normalized vectors are cached after the first call.
+// See ClearCache for details.
 func (e *Embedding) Lookup(vec anyvec.Vector, n int) ([]int, []anyvec.Numeric) {
-	if vec.Len() != e.Vectors.Cols {
-		panic("incorrect vector length")
-	}
-
-	c := e.Vectors.Data.Creator()
-	squares := e.Vectors.Data.Copy()
-	anyvec.Pow(squares, c.MakeNumeric(2))
-	normalizers := anyvec.SumCols(squares, e.Vectors.Rows)
-	anyvec.Pow(normalizers, c.MakeNumeric(-0.5))
-
-	masked := e.Vectors.Data.Copy()
-	anyvec.ScaleChunks(masked, normalizers)
-	normVec := vec.Copy()
-	normVec.Scale(c.NumOps().Div(c.MakeNumeric(1), anyvec.Norm(vec)))
-	anyvec.ScaleRepeated(masked, normVec)
-
-	dots := anyvec.SumCols(masked, e.Vectors.Rows)
-
-	var ids []int
-	var dists []anyvec.Numeric
-	for i := 0; i < n && i < dots.Len(); i++ {
-		idx := anyvec.MaxIndex(dots)
-		ids = append(ids, idx)
+	return e.cosineSearcher().Lookup(vec, n)
+}
 
-		dist := anyvec.Sum(dots.Slice(idx, idx+1))
-		dists = append(dists, dist)
+// LookupBatch is like Lookup, but it answers a query for
+// each row of a matrix using a single matrix product.
+//
+// The memory usage is proportional to the number of
+// queries times the number of token IDs, so very large
+// batches should be split up.
+func (e *Embedding) LookupBatch(queries *anyvec.Matrix, n int) ([][]int,
+	[][]anyvec.Numeric) {
+	return e.cosineSearcher().LookupBatch(queries, n)
+}
 
-		// Make sure we don't get this ID again.
-		dots.Slice(idx, idx+1).AddScalar(c.MakeNumeric(-3))
-	}
-	return ids, dists
+// ClearCache discards the normalized vectors cached by
+// Lookup and LookupBatch.
+//
+// The cache is cleared automatically when Vectors is set
+// to a new matrix or when Normalize is called.
+// However, ClearCache must be called if the data in
+// Vectors is modified in some other way.
+func (e *Embedding) ClearCache() {
+	e.searcherCache().Clear()
 }
 
 // Token returns the token for the word ID.
@@ -109,6 +109,14 @@ func (e *Embedding) Dim() int {
 	return e.Vectors.Cols
 }
 
+func (e *Embedding) cosineSearcher() *wordembed.CosineSearcher {
+	return e.searcherCache().Searcher(e.Vectors)
+}
+
+func (e *Embedding) searcherCache() *wordembed.SearcherCache {
+	return wordembed.LoadSearcherCache(&e.searcher)
+}
+
 // SerializerType returns the unique ID used to serialize
 // an Embedding with the serializer package.
 func (e *Embedding) SerializerType() string {
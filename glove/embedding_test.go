@@ -0,0 +1,54 @@
+package glove
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvec32"
+	"github.com/unixpickle/wordembed"
+)
+
+func TestEmbeddingLookup(t *testing.T) {
+	embed := &Embedding{
+		Tokens: wordembed.TokenSet{"a", "b", "c"},
+		Vectors: &anyvec.Matrix{
+			Data: anyvec32.MakeVectorData([]float32{
+				1, 0,
+				1, 1,
+				0, 3,
+				-1, -1,
+			}),
+			Rows: 4,
+			Cols: 2,
+		},
+	}
+	ids, _ := embed.Lookup(anyvec32.MakeVectorData([]float32{0.1, 1}), 3)
+	if !reflect.DeepEqual(ids, []int{2, 1, 0}) {
+		t.Errorf("unexpected IDs: %v", ids)
+	}
+
+	queries := &anyvec.Matrix{
+		Data: anyvec32.MakeVectorData([]float32{0.1, 1, -2, -1}),
+		Rows: 2,
+		Cols: 2,
+	}
+	batchIDs, _ := embed.LookupBatch(queries, 2)
+	if !reflect.DeepEqual(batchIDs, [][]int{{2, 1}, {3, 2}}) {
+		t.Errorf("unexpected batch IDs: %v", batchIDs)
+	}
+
+	// Copying an Embedding by value should be allowed.
+	embedCopy := *embed
+	ids, _ = embedCopy.Lookup(anyvec32.MakeVectorData([]float32{0.1, 1}), 3)
+	if !reflect.DeepEqual(ids, []int{2, 1, 0}) {
+		t.Errorf("unexpected IDs from copy: %v", ids)
+	}
+
+	embed.Vectors.Data.Slice(0, 2).Scale(float32(-1))
+	embed.ClearCache()
+	ids, _ = embed.Lookup(anyvec32.MakeVectorData([]float32{-1, 0}), 1)
+	if !reflect.DeepEqual(ids, []int{0}) {
+		t.Errorf("unexpected IDs after update: %v", ids)
+	}
+}
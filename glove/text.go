@@ -0,0 +1,170 @@
+package glove
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/essentials"
+	"github.com/unixpickle/wordembed"
+)
+
+// LoadText reads an embedding from a file in the text
+// format used by the released GloVe vectors.
+//
+// See ReadText for details.
+func LoadText(c anyvec.Creator, path string) (*Embedding, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, essentials.AddCtx("load text embedding", err)
+	}
+	defer f.Close()
+	return ReadText(c, f)
+}
+
+// ReadText reads an embedding in the text format used by
+// the released GloVe vectors.
+// Each line contains a word followed by the components of
+// its vector, separated by spaces.
+// There is no header line.
+//
+// The data is read in a single pass, so r may be a pipe
+// or a decompressed stream.
+// Since the vectors must be sorted by word, they are held
+// in memory twice while the embedding is being built.
+//
+// Since the format has no unknown token, the vector for
+// the unknown token ID is the mean of all the vectors.
+// If a word appears more than once, only its first vector
+// is used.
+func ReadText(c anyvec.Creator, r io.Reader) (emb *Embedding, err error) {
+	defer essentials.AddCtxTo("read text embedding", &err)
+
+	words, rows, dim, err := readTextRows(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+	tokens := append(wordembed.TokenSet{}, words...)
+	sort.Strings(tokens)
+	tokens = uniqueTokens(tokens)
+
+	data := make([]float32, tokens.NumIDs()*dim)
+	seen := make([]bool, len(tokens))
+	for i, word := range words {
+		id := tokens.ID(word)
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		copy(data[id*dim:(id+1)*dim], rows[i*dim:(i+1)*dim])
+	}
+
+	// Use the mean vector for the unknown token.
+	unknown := data[len(tokens)*dim:]
+	for i := 0; i < len(tokens); i++ {
+		for j, x := range data[i*dim : (i+1)*dim] {
+			unknown[j] += x
+		}
+	}
+	for j := range unknown {
+		unknown[j] /= float32(len(tokens))
+	}
+
+	return &Embedding{
+		Tokens: tokens,
+		Vectors: &anyvec.Matrix{
+			Data: c.MakeVectorData(wordembed.MakeNumericList(c, data)),
+			Rows: tokens.NumIDs(),
+			Cols: dim,
+		},
+	}, nil
+}
+
+// WriteText writes an embedding in the text format used
+// by the released GloVe vectors.
+//
+// The vector for the unknown token is not written.
+func WriteText(w io.Writer, e *Embedding) error {
+	bufWriter := bufio.NewWriter(w)
+	for id, token := range e.Tokens {
+		fields := []string{token}
+		for _, x := range wordembed.NumericListFloat32(extractRow(e.Vectors, id).Data()) {
+			fields = append(fields, strconv.FormatFloat(float64(x), 'g', -1, 32))
+		}
+		if _, err := bufWriter.WriteString(strings.Join(fields, " ") + "\n"); err != nil {
+			return essentials.AddCtx("write text embedding", err)
+		}
+	}
+	if err := bufWriter.Flush(); err != nil {
+		return essentials.AddCtx("write text embedding", err)
+	}
+	return nil
+}
+
+// readTextRows reads the words and vectors from a GloVe
+// text file, determining the dimensionality from the
+// first line.
+//
+// The vectors are returned in the order they appear, as
+// consecutive rows of a flat slice.
+func readTextRows(r *bufio.Reader) (words []string, rows []float32, dim int, err error) {
+	for lineIdx := 1; ; lineIdx++ {
+		fields, err := readTextFields(r)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, 0, err
+		}
+		if dim == 0 {
+			dim = len(fields) - 1
+			if dim == 0 {
+				return nil, nil, 0, fmt.Errorf("line %d: missing vector", lineIdx)
+			}
+		} else if len(fields) < dim+1 {
+			return nil, nil, 0, fmt.Errorf("line %d: expected %d values", lineIdx, dim)
+		}
+		words = append(words, strings.Join(fields[:len(fields)-dim], " "))
+		for _, field := range fields[len(fields)-dim:] {
+			x, err := strconv.ParseFloat(field, 32)
+			if err != nil {
+				return nil, nil, 0, fmt.Errorf("line %d: %s", lineIdx, err.Error())
+			}
+			rows = append(rows, float32(x))
+		}
+	}
+	if len(words) == 0 {
+		return nil, nil, 0, errors.New("no vectors")
+	}
+	return words, rows, dim, nil
+}
+
+// readTextFields reads the next non-empty line and splits
+// it into fields.
+func readTextFields(r *bufio.Reader) ([]string, error) {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return nil, err
+		}
+		if fields := strings.Fields(line); len(fields) > 0 {
+			return fields, nil
+		}
+	}
+}
+
+// uniqueTokens removes duplicates from a sorted TokenSet.
+func uniqueTokens(t wordembed.TokenSet) wordembed.TokenSet {
+	var res wordembed.TokenSet
+	for i, token := range t {
+		if i == 0 || token != t[i-1] {
+			res = append(res, token)
+		}
+	}
+	return res
+}
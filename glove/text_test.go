@@ -0,0 +1,64 @@
+package glove
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/unixpickle/anyvec/anyvec32"
+	"github.com/unixpickle/wordembed"
+)
+
+func TestReadText(t *testing.T) {
+	data := "the 1 2\n" +
+		"a 3 -4\n" +
+		"\n" +
+		"new york 0.5 0.25\n" +
+		"a 7 7\n"
+	// The reader cannot seek, like a pipe or a gzip stream.
+	r := iotest.OneByteReader(strings.NewReader(data))
+	embed, err := ReadText(anyvec32.CurrentCreator(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedTokens := wordembed.TokenSet{"a", "new york", "the"}
+	if !reflect.DeepEqual(embed.Tokens, expectedTokens) {
+		t.Fatalf("expected tokens %v but got %v", expectedTokens, embed.Tokens)
+	}
+	if embed.Vectors.Rows != embed.Tokens.NumIDs() || embed.Vectors.Cols != 2 {
+		t.Fatalf("unexpected shape %dx%d", embed.Vectors.Rows, embed.Vectors.Cols)
+	}
+	expected := []float32{3, -4, 0.5, 0.25, 1, 2, 1.5, -0.5833333}
+	actual := embed.Vectors.Data.Data().([]float32)
+	for i, x := range expected {
+		if diff := actual[i] - x; diff < -1e-5 || diff > 1e-5 {
+			t.Fatalf("expected %v but got %v", expected, actual)
+		}
+	}
+}
+
+func TestReadTextErrors(t *testing.T) {
+	for _, data := range []string{"", "a\n", "a 1 2\nb 3\n", "a 1 x\n"} {
+		_, err := ReadText(anyvec32.CurrentCreator(), strings.NewReader(data))
+		if err == nil {
+			t.Errorf("expected error for %q", data)
+		}
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	data := "a 3 -4\nnew york 0.5 0.25\nthe 1 2\n"
+	embed, err := ReadText(anyvec32.CurrentCreator(), strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteText(&buf, embed); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != data {
+		t.Errorf("expected %q but got %q", data, buf.String())
+	}
+}
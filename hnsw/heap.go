@@ -0,0 +1,95 @@
+package hnsw
+
+import "container/heap"
+
+type candidate struct {
+	ID  int32
+	Sim float32
+}
+
+// bestFirst sorts candidates from most to least similar.
+type bestFirst []candidate
+
+func (b bestFirst) Len() int {
+	return len(b)
+}
+
+func (b bestFirst) Less(i, j int) bool {
+	return b[i].Sim > b[j].Sim
+}
+
+func (b bestFirst) Swap(i, j int) {
+	b[i], b[j] = b[j], b[i]
+}
+
+// maxHeap is a heap whose root is the most similar
+// candidate.
+type maxHeap []candidate
+
+func (m maxHeap) Len() int {
+	return len(m)
+}
+
+func (m maxHeap) Less(i, j int) bool {
+	return m[i].Sim > m[j].Sim
+}
+
+func (m maxHeap) Swap(i, j int) {
+	m[i], m[j] = m[j], m[i]
+}
+
+func (m *maxHeap) Push(x interface{}) {
+	*m = append(*m, x.(candidate))
+}
+
+func (m *maxHeap) Pop() interface{} {
+	res := (*m)[len(*m)-1]
+	*m = (*m)[:len(*m)-1]
+	return res
+}
+
+func (m *maxHeap) PushCandidate(c candidate) {
+	heap.Push(m, c)
+}
+
+func (m *maxHeap) PopBest() candidate {
+	return heap.Pop(m).(candidate)
+}
+
+// minHeap is a heap whose root is the least similar
+// candidate.
+type minHeap []candidate
+
+func (m minHeap) Len() int {
+	return len(m)
+}
+
+func (m minHeap) Less(i, j int) bool {
+	return m[i].Sim < m[j].Sim
+}
+
+func (m minHeap) Swap(i, j int) {
+	m[i], m[j] = m[j], m[i]
+}
+
+func (m *minHeap) Push(x interface{}) {
+	*m = append(*m, x.(candidate))
+}
+
+func (m *minHeap) Pop() interface{} {
+	res := (*m)[len(*m)-1]
+	*m = (*m)[:len(*m)-1]
+	return res
+}
+
+func (m *minHeap) PushCandidate(c candidate) {
+	heap.Push(m, c)
+}
+
+func (m *minHeap) PopWorst() candidate {
+	return heap.Pop(m).(candidate)
+}
+
+func (m minHeap) Worst() candidate {
+	return m[0]
+}
@@ -0,0 +1,501 @@
+// Package hnsw implements approximate nearest neighbor
+// search for word embeddings using Hierarchical Navigable
+// Small World graphs.
+package hnsw
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/essentials"
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/wordembed"
+)
+
+func init() {
+	serializer.RegisterTypedDeserializer((&Index{}).SerializerType(), DeserializeIndex)
+}
+
+const (
+	defaultM              = 16
+	defaultEfConstruction = 200
+	defaultEfSearch       = 50
+)
+
+// An Index wraps an embedding and answers Lookup queries
+// approximately using an HNSW graph.
+//
+// Similarities are cosine similarities, as in the Lookup
+// methods of the embeddings in this repository.
+// Only the token IDs from 0 up to the first ID with an
+// empty token are indexed, so the unknown token is never
+// returned.
+//
+// All methods except Lookup are forwarded to the wrapped
+// embedding.
+type Index struct {
+	Embedding wordembed.Embedding
+
+	// M is the number of neighbors each node is linked to
+	// in the upper layers.
+	// Nodes in the bottom layer have up to 2*M neighbors.
+	//
+	// M must be at least 2, since the number of layers is
+	// based on log(M).
+	M int
+
+	// EfConstruction is the size of the candidate list used
+	// while building the graph.
+	EfConstruction int
+
+	// EfSearch is the size of the candidate list used for
+	// queries.
+	// Larger values improve recall at the cost of speed.
+	//
+	// If this is 0, a default is used.
+	// The candidate list is never smaller than the number
+	// of requested results.
+	EfSearch int
+
+	dim     int
+	vectors []float32
+
+	// neighbors[node][level] lists the neighbors of a node
+	// in a given layer.
+	neighbors [][][]int32
+	entry     int32
+
+	// locks is only used while the graph is being built.
+	locks     []sync.Mutex
+	entryLock sync.RWMutex
+}
+
+// DeserializeIndex deserializes an Index.
+func DeserializeIndex(d []byte) (index *Index, err error) {
+	defer essentials.AddCtxTo("deserialize Index", &err)
+	var embedding serializer.Serializer
+	var m, efConstruction, efSearch, entry int
+	var levels, links []int32
+	err = serializer.DeserializeAny(d, &embedding, &m, &efConstruction, &efSearch,
+		&entry, &levels, &links)
+	if err != nil {
+		return nil, err
+	}
+	e, ok := embedding.(wordembed.Embedding)
+	if !ok {
+		return nil, errors.New("not a wordembed.Embedding")
+	}
+	if m < 2 || efConstruction <= 0 || efSearch < 0 {
+		return nil, errors.New("invalid parameters")
+	}
+	res := &Index{
+		Embedding:      e,
+		M:              m,
+		EfConstruction: efConstruction,
+		EfSearch:       efSearch,
+		entry:          int32(entry),
+	}
+	res.loadVectors()
+	if res.dim <= 0 || len(levels) != len(res.vectors)/res.dim {
+		return nil, errors.New("node count mismatch")
+	}
+	if (len(levels) > 0 || entry != 0) && (entry < 0 || entry >= len(levels)) {
+		return nil, errors.New("entry point out of range")
+	}
+	res.neighbors = make([][][]int32, len(levels))
+	for node, level := range levels {
+		if level < 0 {
+			return nil, errors.New("invalid level")
+		}
+		res.neighbors[node] = make([][]int32, level+1)
+		for l := range res.neighbors[node] {
+			if len(links) == 0 || links[0] < 0 || int(links[0]) > len(links)-1 {
+				return nil, errors.New("invalid links")
+			}
+			count := int(links[0])
+			for _, neighbor := range links[1 : 1+count] {
+				if neighbor < 0 || int(neighbor) >= len(levels) || int(levels[neighbor]) < l {
+					return nil, errors.New("invalid links")
+				}
+			}
+			if count > 0 {
+				res.neighbors[node][l] = links[1 : 1+count]
+			}
+			links = links[1+count:]
+		}
+	}
+	if len(links) != 0 {
+		return nil, errors.New("invalid links")
+	}
+	return res, nil
+}
+
+// NewIndex builds an Index for an embedding.
+//
+// The m and efConstruction arguments set the fields with
+// the same names.
+// If either one is 0, a default is used.
+// Other values of m below 2 are raised to 2.
+//
+// The graph is built with runtime.GOMAXPROCS(0) workers.
+func NewIndex(e wordembed.Embedding, m, efConstruction int) *Index {
+	if m == 0 {
+		m = defaultM
+	} else if m < 2 {
+		m = 2
+	}
+	if efConstruction == 0 {
+		efConstruction = defaultEfConstruction
+	}
+	res := &Index{Embedding: e, M: m, EfConstruction: efConstruction}
+	res.loadVectors()
+	res.build()
+	return res
+}
+
+// Dim returns the dimensionality of the embedding.
+func (i *Index) Dim() int {
+	return i.Embedding.Dim()
+}
+
+// Embed returns the embedding for the token.
+func (i *Index) Embed(token string) anyvec.Vector {
+	return i.Embedding.Embed(token)
+}
+
+// EmbedID returns the embedding for the token ID.
+func (i *Index) EmbedID(id int) anyvec.Vector {
+	return i.Embedding.EmbedID(id)
+}
+
+// Token returns the token for the token ID.
+func (i *Index) Token(id int) string {
+	return i.Embedding.Token(id)
+}
+
+// Lookup approximately finds the n closest token IDs to
+// the given vector, using cosine similarity.
+// For each ID, it also returns the similarity.
+//
+// If n is greater than the number of indexed tokens, then
+// there will be fewer than n results.
+func (i *Index) Lookup(vec anyvec.Vector, n int) ([]int, []anyvec.Numeric) {
+	if vec.Len() != i.dim {
+		panic("incorrect vector length")
+	}
+	if n <= 0 || len(i.neighbors) == 0 {
+		return nil, nil
+	}
+	query := wordembed.NormalizeFloat32(wordembed.NumericListFloat32(vec.Data()))
+
+	ef := i.EfSearch
+	if ef == 0 {
+		ef = defaultEfSearch
+	}
+	if ef < n {
+		ef = n
+	}
+
+	entry := i.entry
+	for level := len(i.neighbors[entry]) - 1; level > 0; level-- {
+		entry = i.greedySearch(query, entry, level)
+	}
+	results := i.searchLayer(query, entry, ef, 0)
+	if len(results) > n {
+		results = results[:n]
+	}
+
+	c := vec.Creator()
+	ids := make([]int, len(results))
+	sims := make([]anyvec.Numeric, len(results))
+	for j, result := range results {
+		ids[j] = int(result.ID)
+		sims[j] = c.MakeNumeric(float64(result.Sim))
+	}
+	return ids, sims
+}
+
+// SerializerType returns the unique ID used to serialize
+// an Index with the serializer package.
+func (i *Index) SerializerType() string {
+	return "github.com/unixpickle/wordembed/hnsw.Index"
+}
+
+// Serialize serializes the Index.
+//
+// The wrapped embedding is serialized as well, so it must
+// implement serializer.Serializer.
+// The normalized vectors are not stored, since they can
+// be recomputed from the embedding.
+func (i *Index) Serialize() ([]byte, error) {
+	embedding, ok := i.Embedding.(serializer.Serializer)
+	if !ok {
+		return nil, errors.New("serialize Index: embedding is not a serializer.Serializer")
+	}
+	levels := make([]int32, len(i.neighbors))
+	var links []int32
+	for node, layers := range i.neighbors {
+		levels[node] = int32(len(layers) - 1)
+		for _, layer := range layers {
+			links = append(links, int32(len(layer)))
+			links = append(links, layer...)
+		}
+	}
+	return serializer.SerializeAny(
+		embedding,
+		i.M,
+		i.EfConstruction,
+		i.EfSearch,
+		int(i.entry),
+		levels,
+		links,
+	)
+}
+
+// loadVectors stores a normalized copy of every indexed
+// vector.
+func (i *Index) loadVectors() {
+	i.dim = i.Embedding.Dim()
+	i.vectors = nil
+	for id := range wordembed.EmbeddingTokens(i.Embedding) {
+		vec := wordembed.NumericListFloat32(i.Embedding.EmbedID(id).Data())
+		i.vectors = append(i.vectors, wordembed.NormalizeFloat32(vec)...)
+	}
+}
+
+func (i *Index) build() {
+	numNodes := len(i.vectors) / i.dim
+	if numNodes == 0 {
+		return
+	}
+
+	levelScale := 1 / math.Log(float64(i.M))
+	i.neighbors = make([][][]int32, numNodes)
+	for node := range i.neighbors {
+		level := int(-math.Log(1-rand.Float64()) * levelScale)
+		i.neighbors[node] = make([][]int32, level+1)
+	}
+
+	i.locks = make([]sync.Mutex, numNodes)
+	i.entry = 0
+	var nextNode int64
+	var wg sync.WaitGroup
+	for j := 0; j < runtime.GOMAXPROCS(0); j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				node := atomic.AddInt64(&nextNode, 1)
+				if node >= int64(numNodes) {
+					return
+				}
+				i.insert(int32(node))
+			}
+		}()
+	}
+	wg.Wait()
+	i.locks = nil
+}
+
+func (i *Index) insert(node int32) {
+	numLevels := len(i.neighbors[node])
+
+	i.entryLock.RLock()
+	entry := i.entry
+	i.entryLock.RUnlock()
+	if numLevels <= len(i.neighbors[entry]) {
+		i.link(node, entry)
+		return
+	}
+
+	// The node may become the new entry point.
+	// As in hnswlib, entryLock is held for the whole insert
+	// so that nodes above the top layer are inserted one at
+	// a time and get linked to each other.
+	i.entryLock.Lock()
+	defer i.entryLock.Unlock()
+	i.link(node, i.entry)
+	if numLevels > len(i.neighbors[i.entry]) {
+		i.entry = node
+	}
+}
+
+// link connects a node to the graph, starting the search
+// from the given entry point.
+func (i *Index) link(node, entry int32) {
+	query := i.vector(node)
+	numLevels := len(i.neighbors[node])
+	entryLevels := len(i.neighbors[entry])
+	for level := entryLevels - 1; level >= numLevels; level-- {
+		entry = i.greedySearch(query, entry, level)
+	}
+	for level := essentials.MinInt(numLevels, entryLevels) - 1; level >= 0; level-- {
+		candidates := i.searchLayer(query, entry, i.EfConstruction, level)
+		entry = candidates[0].ID
+		neighbors := i.selectNeighbors(candidates, i.M)
+		i.setNeighbors(node, level, neighbors)
+		for _, neighbor := range neighbors {
+			i.addNeighbor(neighbor.ID, node, level)
+		}
+	}
+}
+
+// addNeighbor links node to newNeighbor, pruning node's
+// neighbors if it has too many.
+func (i *Index) addNeighbor(node, newNeighbor int32, level int) {
+	if i.locks != nil {
+		i.locks[node].Lock()
+		defer i.locks[node].Unlock()
+	}
+	neighbors := append(i.neighbors[node][level], newNeighbor)
+	if len(neighbors) <= i.maxNeighbors(level) {
+		i.neighbors[node][level] = neighbors
+		return
+	}
+	vec := i.vector(node)
+	candidates := make([]candidate, len(neighbors))
+	for j, neighbor := range neighbors {
+		candidates[j] = candidate{
+			ID:  neighbor,
+			Sim: wordembed.DotFloat32(vec, i.vector(neighbor)),
+		}
+	}
+	sort.Sort(bestFirst(candidates))
+	selected := i.selectNeighbors(candidates, i.maxNeighbors(level))
+	ids := make([]int32, len(selected))
+	for j, c := range selected {
+		ids[j] = c.ID
+	}
+	i.neighbors[node][level] = ids
+}
+
+func (i *Index) setNeighbors(node int32, level int, neighbors []candidate) {
+	ids := make([]int32, len(neighbors))
+	for j, c := range neighbors {
+		ids[j] = c.ID
+	}
+	if i.locks != nil {
+		i.locks[node].Lock()
+		defer i.locks[node].Unlock()
+	}
+	i.neighbors[node][level] = ids
+}
+
+// layerNeighbors returns the neighbors of a node in a
+// layer.
+// The result must not be modified.
+func (i *Index) layerNeighbors(node int32, level int) []int32 {
+	if i.locks != nil {
+		i.locks[node].Lock()
+		defer i.locks[node].Unlock()
+	}
+	return i.neighbors[node][level]
+}
+
+// selectNeighbors chooses up to m neighbors from a list
+// of candidates sorted from most to least similar.
+//
+// It uses the heuristic from the HNSW paper, preferring
+// candidates which are closer to the query than to the
+// neighbors selected so far.
+// If the heuristic selects fewer than m neighbors, the
+// remaining slots are filled with the most similar
+// candidates that were skipped.
+func (i *Index) selectNeighbors(candidates []candidate, m int) []candidate {
+	var res, skipped []candidate
+	for _, c := range candidates {
+		if len(res) == m {
+			break
+		}
+		vec := i.vector(c.ID)
+		keep := true
+		for _, r := range res {
+			if wordembed.DotFloat32(vec, i.vector(r.ID)) > c.Sim {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			res = append(res, c)
+		} else {
+			skipped = append(skipped, c)
+		}
+	}
+	for _, c := range skipped {
+		if len(res) == m {
+			break
+		}
+		res = append(res, c)
+	}
+	return res
+}
+
+// greedySearch walks a layer towards the query until no
+// neighbor is closer.
+func (i *Index) greedySearch(query []float32, entry int32, level int) int32 {
+	bestSim := wordembed.DotFloat32(query, i.vector(entry))
+	for {
+		changed := false
+		for _, neighbor := range i.layerNeighbors(entry, level) {
+			if sim := wordembed.DotFloat32(query, i.vector(neighbor)); sim > bestSim {
+				bestSim = sim
+				entry = neighbor
+				changed = true
+			}
+		}
+		if !changed {
+			return entry
+		}
+	}
+}
+
+// searchLayer finds up to ef nodes close to the query in
+// a layer.
+// The results are sorted from most to least similar.
+func (i *Index) searchLayer(query []float32, entry int32, ef, level int) []candidate {
+	start := candidate{ID: entry, Sim: wordembed.DotFloat32(query, i.vector(entry))}
+	visited := map[int32]bool{entry: true}
+	candidates := &maxHeap{start}
+	results := &minHeap{start}
+	for candidates.Len() > 0 {
+		current := candidates.PopBest()
+		if results.Len() >= ef && current.Sim < results.Worst().Sim {
+			break
+		}
+		for _, neighbor := range i.layerNeighbors(current.ID, level) {
+			if visited[neighbor] {
+				continue
+			}
+			visited[neighbor] = true
+			sim := wordembed.DotFloat32(query, i.vector(neighbor))
+			if results.Len() < ef || sim > results.Worst().Sim {
+				c := candidate{ID: neighbor, Sim: sim}
+				candidates.PushCandidate(c)
+				results.PushCandidate(c)
+				if results.Len() > ef {
+					results.PopWorst()
+				}
+			}
+		}
+	}
+	res := []candidate(*results)
+	sort.Sort(bestFirst(res))
+	return res
+}
+
+func (i *Index) maxNeighbors(level int) int {
+	if level == 0 {
+		return i.M * 2
+	}
+	return i.M
+}
+
+func (i *Index) vector(node int32) []float32 {
+	return i.vectors[int(node)*i.dim : int(node+1)*i.dim]
+}
@@ -0,0 +1,176 @@
+package hnsw
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvec32"
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/wordembed"
+	"github.com/unixpickle/wordembed/glove"
+	"github.com/unixpickle/wordembed/internal/embedtest"
+)
+
+func TestIndexRecall(t *testing.T) {
+	embedding := embedtest.RandomEmbedding(anyvec32.CurrentCreator(), 2000, 16)
+	index := NewIndex(embedding, 8, 100)
+
+	var hits, total int
+	for i := 0; i < 50; i++ {
+		query := anyvec32.MakeVector(16)
+		anyvec.Rand(query, anyvec.Normal, nil)
+		expected := exactLookup(embedding, query, 10)
+		actual, sims := index.Lookup(query, 10)
+		if len(actual) != 10 {
+			t.Fatalf("expected 10 results but got %d", len(actual))
+		}
+		for j := 1; j < len(sims); j++ {
+			if sims[j].(float32) > sims[j-1].(float32) {
+				t.Fatal("results are not sorted")
+			}
+		}
+		for _, id := range actual {
+			if id >= len(embedding.Tokens) {
+				t.Fatal("unknown token returned")
+			}
+			for _, expectedID := range expected {
+				if id == expectedID {
+					hits++
+				}
+			}
+		}
+		total += len(expected)
+	}
+	if recall := float64(hits) / float64(total); recall < 0.9 {
+		t.Errorf("recall too low: %f", recall)
+	}
+}
+
+func TestIndexSmall(t *testing.T) {
+	embedding := embedtest.RandomEmbedding(anyvec32.CurrentCreator(), 3, 4)
+	index := NewIndex(embedding, 0, 0)
+	ids, _ := index.Lookup(embedding.EmbedID(1), 5)
+	if len(ids) != 3 || ids[0] != 1 {
+		t.Errorf("unexpected results: %v", ids)
+	}
+}
+
+func TestIndexSmallM(t *testing.T) {
+	embedding := embedtest.RandomEmbedding(anyvec32.CurrentCreator(), 50, 4)
+	for _, m := range []int{1, -3} {
+		index := NewIndex(embedding, m, 0)
+		if index.M != 2 {
+			t.Errorf("m=%d: expected M=2 but got %d", m, index.M)
+		}
+		ids, _ := index.Lookup(embedding.EmbedID(7), 1)
+		if len(ids) != 1 || ids[0] != 7 {
+			t.Errorf("m=%d: unexpected results: %v", m, ids)
+		}
+	}
+}
+
+func TestIndexUpperLayersLinked(t *testing.T) {
+	embedding := embedtest.RandomEmbedding(anyvec32.CurrentCreator(), 2000, 8)
+	for trial := 0; trial < 5; trial++ {
+		index := NewIndex(embedding, 2, 20)
+		layerSizes := map[int]int{}
+		for _, layers := range index.neighbors {
+			for level := range layers {
+				layerSizes[level]++
+			}
+		}
+		for node, layers := range index.neighbors {
+			for level, neighbors := range layers {
+				if layerSizes[level] > 1 && len(neighbors) == 0 {
+					t.Fatalf("node %d has no neighbors in layer %d", node, level)
+				}
+			}
+		}
+	}
+}
+
+func TestIndexSerialize(t *testing.T) {
+	embedding := embedtest.RandomEmbedding(anyvec32.CurrentCreator(), 300, 8)
+	index := NewIndex(embedding, 4, 30)
+	index.EfSearch = 17
+	data, err := serializer.SerializeAny(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index1 *Index
+	if err := serializer.DeserializeAny(data, &index1); err != nil {
+		t.Fatal(err)
+	}
+	if index1.M != 4 || index1.EfConstruction != 30 || index1.EfSearch != 17 {
+		t.Error("bad hyper-parameters")
+	}
+	if !reflect.DeepEqual(index1.neighbors, index.neighbors) {
+		t.Error("bad graph")
+	}
+	query := anyvec32.MakeVector(8)
+	anyvec.Rand(query, anyvec.Normal, nil)
+	ids, sims := index.Lookup(query, 5)
+	ids1, sims1 := index1.Lookup(query, 5)
+	if !reflect.DeepEqual(ids, ids1) || !reflect.DeepEqual(sims, sims1) {
+		t.Error("lookup mismatch")
+	}
+}
+
+func TestDeserializeIndexInvalid(t *testing.T) {
+	embedding := embedtest.RandomEmbedding(anyvec32.CurrentCreator(), 3, 4)
+	tests := []struct {
+		M, EfConstruction, EfSearch, Entry int
+
+		Levels []int32
+		Links  []int32
+	}{
+		{4, 30, 0, 3, []int32{0, 0, 0}, []int32{1, 1, 1, 0, 1, 1}},
+		{4, 30, 0, -1, []int32{0, 0, 0}, []int32{1, 1, 1, 0, 1, 1}},
+		{0, 30, 0, 0, []int32{0, 0, 0}, []int32{1, 1, 1, 0, 1, 1}},
+		{1, 30, 0, 0, []int32{0, 0, 0}, []int32{1, 1, 1, 0, 1, 1}},
+		{4, 0, 0, 0, []int32{0, 0, 0}, []int32{1, 1, 1, 0, 1, 1}},
+		{4, 30, -1, 0, []int32{0, 0, 0}, []int32{1, 1, 1, 0, 1, 1}},
+		{4, 30, 0, 0, []int32{0, 0}, []int32{1, 1, 1, 0}},
+		{4, 30, 0, 0, []int32{0, 0, 0}, []int32{1, 3, 1, 0, 1, 1}},
+		{4, 30, 0, 0, []int32{1, 0, 0}, []int32{1, 1, 1, 1, 1, 0, 1, 1}},
+		{4, 30, 0, 0, []int32{0, 0, 0}, []int32{1, 1, 1, 0}},
+	}
+	for i, test := range tests {
+		data, err := serializer.SerializeAny(embedding, test.M, test.EfConstruction,
+			test.EfSearch, test.Entry, test.Levels, test.Links)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := DeserializeIndex(data); err == nil {
+			t.Errorf("test %d: expected an error", i)
+		}
+	}
+}
+
+func BenchmarkIndexLookup(b *testing.B) {
+	embedding := embedtest.RandomEmbedding(anyvec32.CurrentCreator(), 10000, 64)
+	index := NewIndex(embedding, 0, 0)
+	query := anyvec32.MakeVector(64)
+	anyvec.Rand(query, anyvec.Normal, nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index.Lookup(query, 10)
+	}
+}
+
+func exactLookup(e *glove.Embedding, query anyvec.Vector, n int) []int {
+	q := wordembed.NormalizeFloat32(query.Data().([]float32))
+	sims := make([]float32, len(e.Tokens))
+	ids := make([]int, len(e.Tokens))
+	for i := range ids {
+		ids[i] = i
+		row := wordembed.NormalizeFloat32(e.EmbedID(i).Data().([]float32))
+		sims[i] = wordembed.DotFloat32(q, row)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return sims[ids[i]] > sims[ids[j]]
+	})
+	return ids[:n]
+}
@@ -0,0 +1,29 @@
+// Package embedtest provides utilities for testing code
+// which operates on word embeddings.
+package embedtest
+
+import (
+	"fmt"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/wordembed"
+	"github.com/unixpickle/wordembed/glove"
+)
+
+// RandomEmbedding creates an embedding with numTokens
+// tokens, named "token00000", "token00001", etc., and
+// normally distributed vectors.
+// The vectors are created with the given creator, which
+// determines their numeric type.
+func RandomEmbedding(c anyvec.Creator, numTokens, dim int) *glove.Embedding {
+	var tokens wordembed.TokenSet
+	for i := 0; i < numTokens; i++ {
+		tokens = append(tokens, fmt.Sprintf("token%05d", i))
+	}
+	vecs := c.MakeVector(tokens.NumIDs() * dim)
+	anyvec.Rand(vecs, anyvec.Normal, nil)
+	return &glove.Embedding{
+		Tokens:  tokens,
+		Vectors: &anyvec.Matrix{Data: vecs, Rows: tokens.NumIDs(), Cols: dim},
+	}
+}
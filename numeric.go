@@ -0,0 +1,124 @@
+package wordembed
+
+import (
+	"math"
+
+	"github.com/unixpickle/anyvec"
+)
+
+// MakeNumericList creates a numeric list for the creator,
+// avoiding a conversion to float64 when possible.
+//
+// If the creator uses float32, the result shares memory
+// with data.
+func MakeNumericList(c anyvec.Creator, data []float32) anyvec.NumericList {
+	if _, ok := c.MakeNumericList(nil).([]float32); ok {
+		return data
+	}
+	list := make([]float64, len(data))
+	for i, x := range data {
+		list[i] = float64(x)
+	}
+	return c.MakeNumericList(list)
+}
+
+// NumericListFloat32 converts a float32 or float64
+// numeric list to a []float32.
+//
+// If the list is already a []float32, it is returned
+// without being copied.
+func NumericListFloat32(list anyvec.NumericList) []float32 {
+	switch list := list.(type) {
+	case []float32:
+		return list
+	case []float64:
+		res := make([]float32, len(list))
+		for i, x := range list {
+			res[i] = float32(x)
+		}
+		return res
+	default:
+		panic("unsupported numeric type")
+	}
+}
+
+// NumericListFloat64 converts a float32 or float64
+// numeric list to a []float64.
+//
+// If the list is already a []float64, it is returned
+// without being copied.
+func NumericListFloat64(list anyvec.NumericList) []float64 {
+	switch list := list.(type) {
+	case []float64:
+		return list
+	case []float32:
+		res := make([]float64, len(list))
+		for i, x := range list {
+			res[i] = float64(x)
+		}
+		return res
+	default:
+		panic("unsupported numeric type")
+	}
+}
+
+// NumericFloat64 converts a float32 or float64 numeric to
+// a float64.
+func NumericFloat64(n anyvec.Numeric) float64 {
+	switch n := n.(type) {
+	case float64:
+		return n
+	case float32:
+		return float64(n)
+	default:
+		panic("unsupported numeric type")
+	}
+}
+
+// DotFloat32 computes the dot product of two vectors.
+func DotFloat32(v1, v2 []float32) float32 {
+	var res float32
+	for i, x := range v1 {
+		res += x * v2[i]
+	}
+	return res
+}
+
+// DotFloat64 computes the dot product of two vectors.
+func DotFloat64(v1, v2 []float64) float64 {
+	var res float64
+	for i, x := range v1 {
+		res += x * v2[i]
+	}
+	return res
+}
+
+// NormalizeFloat32 creates a copy of a vector scaled to
+// unit length.
+// A zero vector stays zero.
+func NormalizeFloat32(vec []float32) []float32 {
+	res := make([]float32, len(vec))
+	norm := math.Sqrt(float64(DotFloat32(vec, vec)))
+	if norm == 0 {
+		return res
+	}
+	for i, x := range vec {
+		res[i] = float32(float64(x) / norm)
+	}
+	return res
+}
+
+// NormalizeFloat64 creates a copy of a vector scaled to
+// unit length.
+// A zero vector stays zero.
+func NormalizeFloat64(vec []float64) []float64 {
+	res := make([]float64, len(vec))
+	norm := math.Sqrt(DotFloat64(vec, vec))
+	if norm == 0 {
+		return res
+	}
+	for i, x := range vec {
+		res[i] = x / norm
+	}
+	return res
+}
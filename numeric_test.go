@@ -0,0 +1,24 @@
+package wordembed
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/anyvec/anyvec32"
+	"github.com/unixpickle/anyvec/anyvec64"
+)
+
+func TestMakeNumericList(t *testing.T) {
+	data := []float32{1, -2.5, 3}
+	list32 := MakeNumericList(anyvec32.CurrentCreator(), data)
+	if !reflect.DeepEqual(list32, data) {
+		t.Errorf("unexpected float32 list: %v", list32)
+	}
+	list64 := MakeNumericList(anyvec64.CurrentCreator(), data)
+	if !reflect.DeepEqual(list64, []float64{1, -2.5, 3}) {
+		t.Errorf("unexpected float64 list: %v", list64)
+	}
+	if actual := NumericListFloat32(list64); !reflect.DeepEqual(actual, data) {
+		t.Errorf("expected %v but got %v", data, actual)
+	}
+}
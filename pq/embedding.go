@@ -0,0 +1,334 @@
+// Package pq compresses word embeddings with product
+// quantization, as described in Jégou et al., "Product
+// Quantization for Nearest Neighbor Search".
+package pq
+
+import (
+	"errors"
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvecsave"
+	"github.com/unixpickle/essentials"
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/wordembed"
+)
+
+func init() {
+	serializer.RegisterTypedDeserializer((&Embedding{}).SerializerType(),
+		DeserializeEmbedding)
+}
+
+const (
+	defaultCentroids = 256
+	maxCentroids     = 256
+)
+
+// An Embedding is a compressed word embedding.
+//
+// Every vector is split into Subspaces equally sized
+// sub-vectors, and each sub-vector is replaced by the
+// closest centroid in that subspace's codebook.
+// With 256 centroids, each sub-vector is stored in a
+// single byte.
+//
+// The fields should not be modified after the Embedding
+// is created, since derived data is cached.
+type Embedding struct {
+	// Tokens is the list of available words.
+	Tokens wordembed.TokenSet
+
+	// Subspaces is the number of sub-vectors per vector.
+	Subspaces int
+
+	// Centroids stores the codebooks.
+	// Row s*K+k is the k-th centroid of subspace s, where
+	// K is Centroids.Rows/Subspaces.
+	Centroids *anyvec.Matrix
+
+	// Codes stores one centroid index per subspace for
+	// each token ID, including the unknown token.
+	// The codes for ID i are Codes[i*Subspaces:(i+1)*Subspaces].
+	Codes []byte
+
+	centroids []float32
+
+	// norms stores the magnitude of each reconstructed
+	// vector.
+	norms []float32
+}
+
+// DeserializeEmbedding deserializes an Embedding.
+func DeserializeEmbedding(d []byte) (embedding *Embedding, err error) {
+	defer essentials.AddCtxTo("deserialize Embedding", &err)
+	var res Embedding
+	var rows, cols int
+	var centroids *anyvecsave.S
+	var codes serializer.Bytes
+	err = serializer.DeserializeAny(d, &res.Tokens, &res.Subspaces, &rows, &cols,
+		&centroids, &codes)
+	if err != nil {
+		return nil, err
+	}
+	res.Centroids = &anyvec.Matrix{Data: centroids.Vector, Rows: rows, Cols: cols}
+	res.Codes = codes
+	if res.Subspaces <= 0 || rows%res.Subspaces != 0 ||
+		rows/res.Subspaces > maxCentroids {
+		return nil, errors.New("invalid codebook shape")
+	}
+	if len(res.Codes) != res.Tokens.NumIDs()*res.Subspaces {
+		return nil, errors.New("code count mismatch")
+	}
+	for _, code := range res.Codes {
+		if int(code) >= rows/res.Subspaces {
+			return nil, errors.New("code out of range")
+		}
+	}
+	res.loadCache()
+	return &res, nil
+}
+
+// Quantize trains a product quantizer for an embedding and
+// uses it to compress every vector.
+//
+// The embedding's dimensionality must be divisible by the
+// number of subspaces.
+// If numCentroids is 0, 256 centroids are used per
+// subspace.
+// It may not be greater than 256.
+//
+// The codebooks are trained with k-means on (at most) a
+// random sample of vectors, and the subspaces are trained
+// with runtime.GOMAXPROCS(0) workers.
+func Quantize(e wordembed.Embedding, subspaces, numCentroids int) *Embedding {
+	if numCentroids == 0 {
+		numCentroids = defaultCentroids
+	}
+	if subspaces <= 0 || e.Dim()%subspaces != 0 {
+		panic("dimension must be divisible by number of subspaces")
+	}
+	if numCentroids > maxCentroids {
+		panic("too many centroids")
+	}
+
+	tokens := wordembed.TokenSet(append([]string{}, wordembed.EmbeddingTokens(e)...))
+	sort.Strings(tokens)
+	vector := func(id int) []float32 {
+		if id == len(tokens) {
+			return wordembed.NumericListFloat32(e.EmbedID(id).Data())
+		}
+		return wordembed.NumericListFloat32(e.Embed(tokens[id]).Data())
+	}
+
+	subDim := e.Dim() / subspaces
+	numIDs := tokens.NumIDs()
+	numCentroids = essentials.MinInt(numCentroids, numIDs)
+	sample := trainingSample(numIDs, vector)
+	codebooks := make([][]float32, subspaces)
+	parallelFor(subspaces, func(s int) {
+		data := make([]float32, len(sample)*subDim)
+		for i, vec := range sample {
+			copy(data[i*subDim:], vec[s*subDim:(s+1)*subDim])
+		}
+		codebooks[s] = kMeans(data, subDim, numCentroids)
+	})
+
+	var centroids []float32
+	for _, codebook := range codebooks {
+		centroids = append(centroids, codebook...)
+	}
+	c := e.EmbedID(0).Creator()
+	res := &Embedding{
+		Tokens:    tokens,
+		Subspaces: subspaces,
+		Centroids: &anyvec.Matrix{
+			Data: c.MakeVectorData(wordembed.MakeNumericList(c, centroids)),
+			Rows: subspaces * numCentroids,
+			Cols: subDim,
+		},
+		Codes:     make([]byte, numIDs*subspaces),
+		centroids: centroids,
+	}
+	parallelFor(numIDs, func(id int) {
+		vec := vector(id)
+		for s := 0; s < subspaces; s++ {
+			sub := vec[s*subDim : (s+1)*subDim]
+			res.Codes[id*subspaces+s] = byte(nearestCentroid(codebooks[s], sub))
+		}
+	})
+	res.loadCache()
+	return res
+}
+
+// Dim returns the dimensionality of the embedding.
+func (e *Embedding) Dim() int {
+	return e.Centroids.Cols * e.Subspaces
+}
+
+// Embed returns the reconstructed embedding for the token.
+func (e *Embedding) Embed(token string) anyvec.Vector {
+	return e.EmbedID(e.Tokens.ID(token))
+}
+
+// EmbedID returns the reconstructed embedding for the
+// token ID.
+func (e *Embedding) EmbedID(id int) anyvec.Vector {
+	c := e.Centroids.Data.Creator()
+	return c.MakeVectorData(wordembed.MakeNumericList(c, e.reconstruct(id)))
+}
+
+// Token returns the token for the token ID.
+func (e *Embedding) Token(id int) string {
+	return e.Tokens.Token(id)
+}
+
+// Lookup finds the n closest token IDs to the given
+// vector, using cosine similarity.
+// For each ID, it also returns the similarity.
+//
+// Similarities are computed with asymmetric distance
+// computation: the query is not quantized, and its dot
+// product with every centroid is computed once, so each
+// token only costs one table lookup per subspace.
+//
+// If n is greater than the number of IDs, then there will
+// be fewer than n results.
+func (e *Embedding) Lookup(vec anyvec.Vector, n int) ([]int, []anyvec.Numeric) {
+	if vec.Len() != e.Dim() {
+		panic("incorrect vector length")
+	}
+	query := wordembed.NumericListFloat32(vec.Data())
+	queryNorm := math.Sqrt(float64(wordembed.DotFloat32(query, query)))
+
+	subDim := e.Centroids.Cols
+	numCentroids := e.numCentroids()
+	table := make([]float32, e.Subspaces*numCentroids)
+	for s := 0; s < e.Subspaces; s++ {
+		sub := query[s*subDim : (s+1)*subDim]
+		for k := 0; k < numCentroids; k++ {
+			table[s*numCentroids+k] = wordembed.DotFloat32(sub, e.centroid(s, k))
+		}
+	}
+
+	sims := make([]float64, len(e.norms))
+	for id, norm := range e.norms {
+		if norm == 0 || queryNorm == 0 {
+			continue
+		}
+		var sum float32
+		codes := e.Codes[id*e.Subspaces : (id+1)*e.Subspaces]
+		for s, code := range codes {
+			sum += table[s*numCentroids+int(code)]
+		}
+		sims[id] = float64(sum) / (queryNorm * float64(norm))
+	}
+	c := vec.Creator()
+	return wordembed.TopK(c.MakeVectorData(c.MakeNumericList(sims)), n)
+}
+
+// ReconstructionError computes the mean squared Euclidean
+// distance between the vectors of an embedding and their
+// reconstructions.
+//
+// The average is taken over the tokens of e, including the
+// unknown token.
+// Usually, e is the embedding that was quantized.
+func (e *Embedding) ReconstructionError(orig wordembed.Embedding) float64 {
+	if orig.Dim() != e.Dim() {
+		panic("dimension mismatch")
+	}
+	var total float64
+	for id := 0; id < e.Tokens.NumIDs(); id++ {
+		var vec []float32
+		if id == len(e.Tokens) {
+			unknown := orig.EmbedID(len(wordembed.EmbeddingTokens(orig)))
+			vec = wordembed.NumericListFloat32(unknown.Data())
+		} else {
+			vec = wordembed.NumericListFloat32(orig.Embed(e.Tokens[id]).Data())
+		}
+		for i, x := range e.reconstruct(id) {
+			diff := float64(x - vec[i])
+			total += diff * diff
+		}
+	}
+	return total / float64(e.Tokens.NumIDs())
+}
+
+// SerializerType returns the unique ID used to serialize
+// an Embedding with the serializer package.
+func (e *Embedding) SerializerType() string {
+	return "github.com/unixpickle/wordembed/pq.Embedding"
+}
+
+// Serialize serializes the Embedding.
+func (e *Embedding) Serialize() ([]byte, error) {
+	return serializer.SerializeAny(
+		e.Tokens,
+		e.Subspaces,
+		e.Centroids.Rows,
+		e.Centroids.Cols,
+		&anyvecsave.S{Vector: e.Centroids.Data},
+		serializer.Bytes(e.Codes),
+	)
+}
+
+func (e *Embedding) loadCache() {
+	e.centroids = wordembed.NumericListFloat32(e.Centroids.Data.Data())
+	numCentroids := e.numCentroids()
+	centroidNorms := make([]float32, e.Subspaces*numCentroids)
+	for s := 0; s < e.Subspaces; s++ {
+		for k := 0; k < numCentroids; k++ {
+			centroid := e.centroid(s, k)
+			centroidNorms[s*numCentroids+k] = wordembed.DotFloat32(centroid, centroid)
+		}
+	}
+	e.norms = make([]float32, e.Tokens.NumIDs())
+	for id := range e.norms {
+		var sum float32
+		for s, code := range e.Codes[id*e.Subspaces : (id+1)*e.Subspaces] {
+			sum += centroidNorms[s*numCentroids+int(code)]
+		}
+		e.norms[id] = float32(math.Sqrt(float64(sum)))
+	}
+}
+
+func (e *Embedding) numCentroids() int {
+	return e.Centroids.Rows / e.Subspaces
+}
+
+func (e *Embedding) centroid(subspace, index int) []float32 {
+	row := subspace*e.numCentroids() + index
+	return e.centroids[row*e.Centroids.Cols : (row+1)*e.Centroids.Cols]
+}
+
+func (e *Embedding) reconstruct(id int) []float32 {
+	res := make([]float32, 0, e.Dim())
+	for s, code := range e.Codes[id*e.Subspaces : (id+1)*e.Subspaces] {
+		res = append(res, e.centroid(s, int(code))...)
+	}
+	return res
+}
+
+// parallelFor calls f for every index in [0, n) using
+// runtime.GOMAXPROCS(0) workers.
+func parallelFor(n int, f func(i int)) {
+	indices := make(chan int, n)
+	for i := 0; i < n; i++ {
+		indices <- i
+	}
+	close(indices)
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.GOMAXPROCS(0); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				f(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,131 @@
+package pq
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvec32"
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/wordembed/glove"
+	"github.com/unixpickle/wordembed/internal/embedtest"
+)
+
+func TestQuantizeExact(t *testing.T) {
+	// With at least one centroid per vector, every vector
+	// can be reconstructed exactly.
+	embedding := embedtest.RandomEmbedding(anyvec32.CurrentCreator(), 8, 6)
+	quantized := Quantize(embedding, 3, 0)
+	if quantized.Dim() != 6 {
+		t.Fatalf("expected dim 6 but got %d", quantized.Dim())
+	}
+	if err := quantized.ReconstructionError(embedding); err > 1e-8 {
+		t.Errorf("unexpected reconstruction error: %f", err)
+	}
+	for id := 0; id < embedding.Tokens.NumIDs(); id++ {
+		if quantized.Token(id) != embedding.Token(id) {
+			t.Fatalf("token %d: expected %s but got %s", id, embedding.Token(id),
+				quantized.Token(id))
+		}
+		expected := embedding.EmbedID(id).Data().([]float32)
+		actual := quantized.EmbedID(id).Data().([]float32)
+		for i, x := range expected {
+			if math.Abs(float64(x-actual[i])) > 1e-5 {
+				t.Fatalf("token %d: expected %v but got %v", id, expected, actual)
+			}
+		}
+	}
+}
+
+func TestQuantizeError(t *testing.T) {
+	embedding := embedtest.RandomEmbedding(anyvec32.CurrentCreator(), 1000, 16)
+	coarse := Quantize(embedding, 4, 4)
+	fine := Quantize(embedding, 4, 64)
+
+	// The error of the all-zero reconstruction.
+	var baseline float64
+	for _, x := range embedding.Vectors.Data.Data().([]float32) {
+		baseline += float64(x * x)
+	}
+	baseline /= float64(embedding.Vectors.Rows)
+
+	coarseErr := coarse.ReconstructionError(embedding)
+	fineErr := fine.ReconstructionError(embedding)
+	if coarseErr >= baseline {
+		t.Errorf("coarse error %f is not below baseline %f", coarseErr, baseline)
+	}
+	if fineErr >= coarseErr {
+		t.Errorf("fine error %f is not below coarse error %f", fineErr, coarseErr)
+	}
+}
+
+func TestEmbeddingLookup(t *testing.T) {
+	embedding := embedtest.RandomEmbedding(anyvec32.CurrentCreator(), 300, 8)
+	quantized := Quantize(embedding, 4, 16)
+
+	// Asymmetric lookups should match exact lookups over the
+	// reconstructed vectors.
+	var rows []anyvec.Vector
+	for id := 0; id < quantized.Tokens.NumIDs(); id++ {
+		rows = append(rows, quantized.EmbedID(id))
+	}
+	reconstructed := &glove.Embedding{
+		Tokens: quantized.Tokens,
+		Vectors: &anyvec.Matrix{
+			Data: anyvec32.CurrentCreator().Concat(rows...),
+			Rows: len(rows),
+			Cols: 8,
+		},
+	}
+
+	for i := 0; i < 10; i++ {
+		query := anyvec32.MakeVector(8)
+		anyvec.Rand(query, anyvec.Normal, nil)
+		expectedIDs, expectedSims := reconstructed.Lookup(query, 5)
+		actualIDs, actualSims := quantized.Lookup(query, 5)
+		if !reflect.DeepEqual(expectedIDs, actualIDs) {
+			t.Fatalf("expected %v but got %v", expectedIDs, actualIDs)
+		}
+		for j, sim := range expectedSims {
+			if math.Abs(float64(sim.(float32)-actualSims[j].(float32))) > 1e-4 {
+				t.Fatalf("expected %v but got %v", expectedSims, actualSims)
+			}
+		}
+	}
+}
+
+func TestEmbeddingSerialize(t *testing.T) {
+	embedding := embedtest.RandomEmbedding(anyvec32.CurrentCreator(), 100, 8)
+	quantized := Quantize(embedding, 2, 8)
+	data, err := serializer.SerializeAny(quantized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded *Embedding
+	if err := serializer.DeserializeAny(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.Tokens, quantized.Tokens) ||
+		decoded.Subspaces != quantized.Subspaces ||
+		!reflect.DeepEqual(decoded.Codes, quantized.Codes) ||
+		!reflect.DeepEqual(decoded.Centroids.Data.Data(), quantized.Centroids.Data.Data()) {
+		t.Fatal("embedding changed")
+	}
+	query := embedding.EmbedID(3)
+	expected, _ := quantized.Lookup(query, 3)
+	actual, _ := decoded.Lookup(query, 3)
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v but got %v", expected, actual)
+	}
+}
+
+func BenchmarkLookup(b *testing.B) {
+	embedding := embedtest.RandomEmbedding(anyvec32.CurrentCreator(), 10000, 64)
+	quantized := Quantize(embedding, 16, 0)
+	query := embedding.EmbedID(0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		quantized.Lookup(query, 10)
+	}
+}
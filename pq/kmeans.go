@@ -0,0 +1,118 @@
+package pq
+
+import (
+	"math"
+	"math/rand"
+)
+
+const (
+	// maxTrainingRows is the maximum number of vectors used
+	// to train the codebooks.
+	maxTrainingRows = 1 << 16
+
+	kMeansIterations = 25
+)
+
+// trainingSample selects a random subset of the vectors
+// to train the codebooks on.
+func trainingSample(numIDs int, vector func(id int) []float32) [][]float32 {
+	ids := rand.Perm(numIDs)
+	if len(ids) > maxTrainingRows {
+		ids = ids[:maxTrainingRows]
+	}
+	res := make([][]float32, len(ids))
+	for i, id := range ids {
+		res[i] = vector(id)
+	}
+	return res
+}
+
+// kMeans clusters the rows of a row-major matrix and
+// returns the centroids as a row-major matrix.
+//
+// The centroids are initialized to distinct random rows.
+// If a cluster becomes empty, its centroid is moved to the
+// row which is farthest from its own centroid.
+func kMeans(data []float32, dim, k int) []float32 {
+	numRows := len(data) / dim
+	row := func(i int) []float32 {
+		return data[i*dim : (i+1)*dim]
+	}
+
+	centroids := make([]float32, k*dim)
+	for i, rowIdx := range rand.Perm(numRows)[:k] {
+		copy(centroids[i*dim:], row(rowIdx))
+	}
+
+	assignments := make([]int, numRows)
+	for i := range assignments {
+		assignments[i] = -1
+	}
+	sums := make([]float64, k*dim)
+	counts := make([]int, k)
+	for iter := 0; iter < kMeansIterations; iter++ {
+		changed := false
+		farthestRow, farthestDist := 0, -1.0
+		for i := range assignments {
+			vec := row(i)
+			idx := nearestCentroid(centroids, vec)
+			if idx != assignments[i] {
+				assignments[i] = idx
+				changed = true
+			}
+			if d := squaredDist(centroids[idx*dim:(idx+1)*dim], vec); d > farthestDist {
+				farthestRow, farthestDist = i, d
+			}
+		}
+		if !changed {
+			break
+		}
+
+		for i := range sums {
+			sums[i] = 0
+		}
+		for i := range counts {
+			counts[i] = 0
+		}
+		for i, idx := range assignments {
+			counts[idx]++
+			for j, x := range row(i) {
+				sums[idx*dim+j] += float64(x)
+			}
+		}
+		for idx, count := range counts {
+			centroid := centroids[idx*dim : (idx+1)*dim]
+			if count == 0 {
+				copy(centroid, row(farthestRow))
+				continue
+			}
+			for j := range centroid {
+				centroid[j] = float32(sums[idx*dim+j] / float64(count))
+			}
+		}
+	}
+	return centroids
+}
+
+// nearestCentroid finds the index of the centroid closest
+// to a vector.
+func nearestCentroid(centroids, vec []float32) int {
+	dim := len(vec)
+	bestIdx := 0
+	bestDist := math.Inf(1)
+	for i := 0; i < len(centroids)/dim; i++ {
+		if d := squaredDist(centroids[i*dim:(i+1)*dim], vec); d < bestDist {
+			bestIdx, bestDist = i, d
+		}
+	}
+	return bestIdx
+}
+
+func squaredDist(v1, v2 []float32) float64 {
+	var res float64
+	for i, x := range v1 {
+		diff := float64(x - v2[i])
+		res += diff * diff
+	}
+	return res
+}
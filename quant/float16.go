@@ -0,0 +1,227 @@
+package quant
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sync"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvecsave"
+	"github.com/unixpickle/essentials"
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/wordembed"
+)
+
+func init() {
+	serializer.RegisterTypedDeserializer((&Float16Embedding{}).SerializerType(),
+		DeserializeFloat16Embedding)
+}
+
+// A Float16Embedding stores every vector component as an
+// IEEE 754 half-precision float.
+//
+// This uses half of the memory of a float32 embedding.
+type Float16Embedding struct {
+	// Tokens is the list of available words.
+	Tokens wordembed.TokenSet
+
+	// Cols is the dimensionality of the embedding.
+	Cols int
+
+	// Values stores the bits of the components, with one
+	// row per token ID, including the unknown token.
+	Values []uint16
+
+	// Creator is used to create dequantized vectors.
+	Creator anyvec.Creator
+
+	normsOnce sync.Once
+	norms     []float32
+}
+
+// DeserializeFloat16Embedding deserializes a
+// Float16Embedding.
+func DeserializeFloat16Embedding(d []byte) (embedding *Float16Embedding, err error) {
+	defer essentials.AddCtxTo("deserialize Float16Embedding", &err)
+	var res Float16Embedding
+	var values serializer.Bytes
+	var creator *anyvecsave.S
+	err = serializer.DeserializeAny(d, &res.Tokens, &res.Cols, &values, &creator)
+	if err != nil {
+		return nil, err
+	}
+	if res.Creator, err = deserializeCreator(creator); err != nil {
+		return nil, err
+	}
+	if len(values) != 2*res.Tokens.NumIDs()*res.Cols {
+		return nil, errors.New("data size mismatch")
+	}
+	res.Values = make([]uint16, len(values)/2)
+	for i := range res.Values {
+		res.Values[i] = binary.LittleEndian.Uint16(values[i*2:])
+	}
+	return &res, nil
+}
+
+// NewFloat16Embedding converts an embedding to half
+// precision.
+//
+// Components are rounded to the nearest representable
+// value.
+// Components too large for half precision become
+// infinite.
+//
+// For a word2vec.Embed, wrap it in a word2vec.Embedding.
+func NewFloat16Embedding(e wordembed.Embedding) *Float16Embedding {
+	res := &Float16Embedding{Cols: e.Dim(), Creator: e.EmbedID(0).Creator()}
+	res.Tokens = sourceRows(e, func(id int, vec []float32) {
+		for _, x := range vec {
+			res.Values = append(res.Values, float32ToFloat16(x))
+		}
+	})
+	return res
+}
+
+// Dim returns the dimensionality of the embedding.
+func (f *Float16Embedding) Dim() int {
+	return f.Cols
+}
+
+// Embed returns the dequantized embedding for the token.
+func (f *Float16Embedding) Embed(token string) anyvec.Vector {
+	return f.EmbedID(f.Tokens.ID(token))
+}
+
+// EmbedID returns the dequantized embedding for the token
+// ID.
+func (f *Float16Embedding) EmbedID(id int) anyvec.Vector {
+	return f.Creator.MakeVectorData(wordembed.MakeNumericList(f.Creator, f.row(id)))
+}
+
+// Token returns the token for the token ID.
+func (f *Float16Embedding) Token(id int) string {
+	return f.Tokens.Token(id)
+}
+
+// Lookup finds the n closest token IDs to the given
+// vector, using cosine similarity.
+// For each ID, it also returns the similarity.
+//
+// The vectors are dequantized one at a time, so that the
+// memory savings are preserved.
+//
+// If n is greater than the number of IDs, then there will
+// be fewer than n results.
+func (f *Float16Embedding) Lookup(vec anyvec.Vector, n int) ([]int, []anyvec.Numeric) {
+	if vec.Len() != f.Cols {
+		panic("incorrect vector length")
+	}
+	f.normsOnce.Do(func() {
+		f.norms = make([]float32, f.Tokens.NumIDs())
+		for row := range f.norms {
+			r := f.row(row)
+			f.norms[row] = float32(math.Sqrt(float64(wordembed.DotFloat32(r, r))))
+		}
+	})
+	return cosineLookup(vec, n, f.norms, func(row int, query []float32) float32 {
+		var sum float32
+		for j, x := range f.Values[row*f.Cols : (row+1)*f.Cols] {
+			sum += float16ToFloat32(x) * query[j]
+		}
+		return sum
+	})
+}
+
+// SerializerType returns the unique ID used to serialize
+// a Float16Embedding with the serializer package.
+func (f *Float16Embedding) SerializerType() string {
+	return "github.com/unixpickle/wordembed/quant.Float16Embedding"
+}
+
+// Serialize serializes the Float16Embedding.
+func (f *Float16Embedding) Serialize() ([]byte, error) {
+	values := make([]byte, len(f.Values)*2)
+	for i, x := range f.Values {
+		binary.LittleEndian.PutUint16(values[i*2:], x)
+	}
+	return serializer.SerializeAny(
+		f.Tokens,
+		f.Cols,
+		serializer.Bytes(values),
+		serializeCreator(f.Creator),
+	)
+}
+
+func (f *Float16Embedding) row(id int) []float32 {
+	res := make([]float32, f.Cols)
+	for j, x := range f.Values[id*f.Cols : (id+1)*f.Cols] {
+		res[j] = float16ToFloat32(x)
+	}
+	return res
+}
+
+// float32ToFloat16 converts a float32 to the bits of the
+// nearest half-precision float, rounding ties to even.
+func float32ToFloat16(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int(bits>>23) & 0xff
+	mant := bits & 0x7fffff
+
+	if exp == 0xff {
+		if mant != 0 {
+			return sign | 0x7e00
+		}
+		return sign | 0x7c00
+	}
+
+	halfExp := exp - 127 + 15
+	if halfExp >= 0x1f {
+		return sign | 0x7c00
+	}
+	if halfExp <= 0 {
+		// The result is subnormal or zero.
+		if halfExp < -10 {
+			return sign
+		}
+		mant |= 0x800000
+		shift := uint(14 - halfExp)
+		half := mant >> shift
+		remainder := mant & (1<<shift - 1)
+		halfway := uint32(1) << (shift - 1)
+		if remainder > halfway || (remainder == halfway && half&1 == 1) {
+			half++
+		}
+		return sign | uint16(half)
+	}
+
+	// A carry out of the mantissa correctly increments the
+	// exponent, possibly producing infinity.
+	half := uint32(halfExp)<<10 | mant>>13
+	remainder := mant & 0x1fff
+	if remainder > 0x1000 || (remainder == 0x1000 && half&1 == 1) {
+		half++
+	}
+	return sign | uint16(half)
+}
+
+// float16ToFloat32 converts the bits of a half-precision
+// float to a float32.
+func float16ToFloat32(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+	switch exp {
+	case 0:
+		res := float32(mant) / (1 << 24)
+		if sign != 0 {
+			return -res
+		}
+		return res
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	default:
+		return math.Float32frombits(sign | (exp+112)<<23 | mant<<13)
+	}
+}
@@ -0,0 +1,175 @@
+package quant
+
+import (
+	"errors"
+	"math"
+	"sync"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvecsave"
+	"github.com/unixpickle/essentials"
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/wordembed"
+)
+
+func init() {
+	serializer.RegisterTypedDeserializer((&Int8Embedding{}).SerializerType(),
+		DeserializeInt8Embedding)
+}
+
+// An Int8Embedding stores every vector as 8-bit integers
+// with a per-vector scale.
+//
+// This uses roughly a quarter of the memory of a float32
+// embedding.
+type Int8Embedding struct {
+	// Tokens is the list of available words.
+	Tokens wordembed.TokenSet
+
+	// Cols is the dimensionality of the embedding.
+	Cols int
+
+	// Values stores one row per token ID, including the
+	// unknown token.
+	// Component j of row i is Values[i*Cols+j]*Scales[i].
+	Values []int8
+	Scales []float32
+
+	// Creator is used to create dequantized vectors.
+	Creator anyvec.Creator
+
+	normsOnce sync.Once
+	norms     []float32
+}
+
+// DeserializeInt8Embedding deserializes an Int8Embedding.
+func DeserializeInt8Embedding(d []byte) (embedding *Int8Embedding, err error) {
+	defer essentials.AddCtxTo("deserialize Int8Embedding", &err)
+	var res Int8Embedding
+	var values serializer.Bytes
+	var creator *anyvecsave.S
+	err = serializer.DeserializeAny(d, &res.Tokens, &res.Cols, &values, &res.Scales,
+		&creator)
+	if err != nil {
+		return nil, err
+	}
+	if res.Creator, err = deserializeCreator(creator); err != nil {
+		return nil, err
+	}
+	numIDs := res.Tokens.NumIDs()
+	if len(values) != numIDs*res.Cols || len(res.Scales) != numIDs {
+		return nil, errors.New("data size mismatch")
+	}
+	res.Values = make([]int8, len(values))
+	for i, x := range values {
+		res.Values[i] = int8(x)
+	}
+	return &res, nil
+}
+
+// NewInt8Embedding quantizes an embedding.
+//
+// Each vector is scaled so that its largest component (in
+// absolute value) maps to 127, and its components are then
+// rounded to the nearest integer.
+//
+// For a word2vec.Embed, wrap it in a word2vec.Embedding.
+func NewInt8Embedding(e wordembed.Embedding) *Int8Embedding {
+	res := &Int8Embedding{Cols: e.Dim(), Creator: e.EmbedID(0).Creator()}
+	res.Tokens = sourceRows(e, func(id int, vec []float32) {
+		var maxAbs float64
+		for _, x := range vec {
+			maxAbs = math.Max(maxAbs, math.Abs(float64(x)))
+		}
+		scale := maxAbs / math.MaxInt8
+		for _, x := range vec {
+			var value int8
+			if scale != 0 {
+				value = int8(math.Round(float64(x) / scale))
+			}
+			res.Values = append(res.Values, value)
+		}
+		res.Scales = append(res.Scales, float32(scale))
+	})
+	return res
+}
+
+// Dim returns the dimensionality of the embedding.
+func (i *Int8Embedding) Dim() int {
+	return i.Cols
+}
+
+// Embed returns the dequantized embedding for the token.
+func (i *Int8Embedding) Embed(token string) anyvec.Vector {
+	return i.EmbedID(i.Tokens.ID(token))
+}
+
+// EmbedID returns the dequantized embedding for the token
+// ID.
+func (i *Int8Embedding) EmbedID(id int) anyvec.Vector {
+	values := i.Values[id*i.Cols : (id+1)*i.Cols]
+	scale := i.Scales[id]
+	res := make([]float32, i.Cols)
+	for j, x := range values {
+		res[j] = float32(x) * scale
+	}
+	return i.Creator.MakeVectorData(wordembed.MakeNumericList(i.Creator, res))
+}
+
+// Token returns the token for the token ID.
+func (i *Int8Embedding) Token(id int) string {
+	return i.Tokens.Token(id)
+}
+
+// Lookup finds the n closest token IDs to the given
+// vector, using cosine similarity.
+// For each ID, it also returns the similarity.
+//
+// The vectors are dequantized one at a time, so that the
+// memory savings are preserved.
+//
+// If n is greater than the number of IDs, then there will
+// be fewer than n results.
+func (i *Int8Embedding) Lookup(vec anyvec.Vector, n int) ([]int, []anyvec.Numeric) {
+	if vec.Len() != i.Cols {
+		panic("incorrect vector length")
+	}
+	i.normsOnce.Do(func() {
+		i.norms = make([]float32, len(i.Scales))
+		for row, scale := range i.Scales {
+			var sum float64
+			for _, x := range i.Values[row*i.Cols : (row+1)*i.Cols] {
+				sum += float64(x) * float64(x)
+			}
+			i.norms[row] = float32(math.Sqrt(sum) * float64(scale))
+		}
+	})
+	return cosineLookup(vec, n, i.norms, func(row int, query []float32) float32 {
+		var sum float32
+		for j, x := range i.Values[row*i.Cols : (row+1)*i.Cols] {
+			sum += float32(x) * query[j]
+		}
+		return sum * i.Scales[row]
+	})
+}
+
+// SerializerType returns the unique ID used to serialize
+// an Int8Embedding with the serializer package.
+func (i *Int8Embedding) SerializerType() string {
+	return "github.com/unixpickle/wordembed/quant.Int8Embedding"
+}
+
+// Serialize serializes the Int8Embedding.
+func (i *Int8Embedding) Serialize() ([]byte, error) {
+	values := make([]byte, len(i.Values))
+	for j, x := range i.Values {
+		values[j] = byte(x)
+	}
+	return serializer.SerializeAny(
+		i.Tokens,
+		i.Cols,
+		serializer.Bytes(values),
+		i.Scales,
+		serializeCreator(i.Creator),
+	)
+}
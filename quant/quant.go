@@ -0,0 +1,67 @@
+// Package quant stores word embeddings with reduced
+// numerical precision to save memory.
+//
+// Unlike product quantization, every component of every
+// vector is stored individually, so the vectors are
+// reconstructed almost exactly.
+package quant
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvecsave"
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/wordembed"
+)
+
+// sourceRows reads the tokens and vectors of an embedding.
+//
+// The tokens are sorted, and the vector for each token ID
+// is passed to f, including the unknown token's vector.
+func sourceRows(e wordembed.Embedding, f func(id int, vec []float32)) wordembed.TokenSet {
+	tokens := wordembed.TokenSet(append([]string{}, wordembed.EmbeddingTokens(e)...))
+	sort.Strings(tokens)
+	for id, token := range tokens {
+		f(id, wordembed.NumericListFloat32(e.Embed(token).Data()))
+	}
+	f(len(tokens), wordembed.NumericListFloat32(e.EmbedID(len(tokens)).Data()))
+	return tokens
+}
+
+// cosineLookup finds the n rows with the highest cosine
+// similarity to a query.
+//
+// The rows are never dequantized all at once.
+// Instead, rowDot computes the dot product of the query
+// with a single row, and norms stores the magnitude of
+// every row.
+func cosineLookup(query anyvec.Vector, n int, norms []float32,
+	rowDot func(row int, query []float32) float32) ([]int, []anyvec.Numeric) {
+	q := wordembed.NumericListFloat32(query.Data())
+	queryNorm := math.Sqrt(float64(wordembed.DotFloat32(q, q)))
+	sims := make([]float64, len(norms))
+	for row, norm := range norms {
+		if norm != 0 && queryNorm != 0 {
+			sims[row] = float64(rowDot(row, q)) / (queryNorm * float64(norm))
+		}
+	}
+	c := query.Creator()
+	return wordembed.TopK(c.MakeVectorData(c.MakeNumericList(sims)), n)
+}
+
+// serializeCreator records the numeric type of a Creator
+// by serializing an empty vector.
+func serializeCreator(c anyvec.Creator) serializer.Serializer {
+	return &anyvecsave.S{Vector: c.MakeVector(0)}
+}
+
+// deserializeCreator is the inverse of serializeCreator.
+func deserializeCreator(s *anyvecsave.S) (anyvec.Creator, error) {
+	if s == nil || s.Vector == nil {
+		return nil, errors.New("missing creator")
+	}
+	return s.Vector.Creator(), nil
+}
@@ -0,0 +1,145 @@
+package quant
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec/anyvec32"
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/wordembed"
+	"github.com/unixpickle/wordembed/internal/embedtest"
+	"github.com/unixpickle/wordembed/word2vec"
+)
+
+func TestConvert(t *testing.T) {
+	gloveEmbedding := embedtest.RandomEmbedding(anyvec32.CurrentCreator(), 50, 10)
+	w2vEmbedding := &word2vec.Embedding{
+		Model: &word2vec.Embed{
+			Matrix: anydiff.NewVar(gloveEmbedding.Vectors.Data.Slice(0, 50*10)),
+			Words:  gloveEmbedding.Tokens,
+		},
+	}
+	for _, source := range []wordembed.Embedding{gloveEmbedding, w2vEmbedding} {
+		for _, test := range []struct {
+			Name      string
+			Embedding wordembed.Embedding
+			Tolerance float64
+		}{
+			{"int8", NewInt8Embedding(source), 0.02},
+			{"float16", NewFloat16Embedding(source), 2e-3},
+		} {
+			if test.Embedding.Dim() != 10 {
+				t.Fatalf("%s: expected dim 10 but got %d", test.Name, test.Embedding.Dim())
+			}
+			for id := 0; id <= 50; id++ {
+				if test.Embedding.Token(id) != source.Token(id) {
+					t.Fatalf("%s: bad token for ID %d", test.Name, id)
+				}
+				expected := source.EmbedID(id).Data().([]float32)
+				actual := test.Embedding.EmbedID(id).Data().([]float32)
+				for i, x := range expected {
+					if math.Abs(float64(x-actual[i])) > test.Tolerance {
+						t.Fatalf("%s: ID %d: expected %v but got %v", test.Name, id,
+							expected, actual)
+					}
+				}
+			}
+			expected := source.Embed("token00007").Data().([]float32)
+			actual := test.Embedding.Embed("token00007").Data().([]float32)
+			if math.Abs(float64(expected[0]-actual[0])) > test.Tolerance {
+				t.Errorf("%s: bad result from Embed", test.Name)
+			}
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	source := embedtest.RandomEmbedding(anyvec32.CurrentCreator(), 200, 16)
+	for _, e := range []wordembed.Embedding{
+		NewInt8Embedding(source),
+		NewFloat16Embedding(source),
+	} {
+		for i := 0; i < 10; i++ {
+			query := source.EmbedID(i * 10)
+			ids, sims := e.Lookup(query, 3)
+			expectedIDs, expectedSims := source.Lookup(query, 3)
+			if len(ids) != 3 || ids[0] != i*10 {
+				t.Fatalf("unexpected results %v (expected %v)", ids, expectedIDs)
+			}
+			if math.Abs(float64(sims[0].(float32)-expectedSims[0].(float32))) > 1e-3 {
+				t.Fatalf("expected similarity %v but got %v", expectedSims[0], sims[0])
+			}
+		}
+	}
+}
+
+func TestSerialize(t *testing.T) {
+	source := embedtest.RandomEmbedding(anyvec32.CurrentCreator(), 20, 6)
+	for _, e := range []serializer.Serializer{
+		NewInt8Embedding(source),
+		NewFloat16Embedding(source),
+	} {
+		data, err := serializer.SerializeAny(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded serializer.Serializer
+		if err := serializer.DeserializeAny(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		original := e.(wordembed.Embedding)
+		embedding, ok := decoded.(wordembed.Embedding)
+		if !ok {
+			t.Fatalf("unexpected type: %T", decoded)
+		}
+		for id := 0; id <= 20; id++ {
+			expected := original.EmbedID(id).Data()
+			actual := embedding.EmbedID(id).Data()
+			if !reflect.DeepEqual(expected, actual) {
+				t.Fatalf("ID %d: expected %v but got %v", id, expected, actual)
+			}
+		}
+	}
+}
+
+func TestFloat16(t *testing.T) {
+	for _, test := range []struct {
+		Value float32
+		Bits  uint16
+	}{
+		{0, 0},
+		{1, 0x3c00},
+		{-2, 0xc000},
+		{0.5, 0x3800},
+		{65504, 0x7bff},
+		{65520, 0x7c00},
+		{1e10, 0x7c00},
+		{float32(math.Inf(-1)), 0xfc00},
+		{0.1, 0x2e66},
+		{1.0 / (1 << 24), 0x0001},
+		{1.0 / (1 << 26), 0},
+		{1 + 1.0/2048, 0x3c00},
+		{1 + 3.0/2048, 0x3c02},
+	} {
+		if bits := float32ToFloat16(test.Value); bits != test.Bits {
+			t.Errorf("value %v: expected 0x%04x but got 0x%04x", test.Value, test.Bits, bits)
+		}
+	}
+	if bits := float32ToFloat16(float32(math.NaN())); bits&0x7c00 != 0x7c00 || bits&0x3ff == 0 {
+		t.Errorf("bad NaN: 0x%04x", bits)
+	}
+
+	// Every finite half-precision value should survive a
+	// round trip.
+	for i := 0; i < 1<<16; i++ {
+		bits := uint16(i)
+		if bits&0x7c00 == 0x7c00 {
+			continue
+		}
+		if actual := float32ToFloat16(float16ToFloat32(bits)); actual != bits {
+			t.Fatalf("0x%04x became 0x%04x", bits, actual)
+		}
+	}
+}
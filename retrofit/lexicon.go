@@ -0,0 +1,96 @@
+package retrofit
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/unixpickle/essentials"
+)
+
+// A Lexicon is a graph which maps each word to a list of
+// related words.
+//
+// Edges are directed, as in the original retrofitting
+// implementation, so a word is only pulled towards the
+// words in its own list.
+// See Symmetrize for undirected graphs.
+type Lexicon map[string][]string
+
+// LoadLexicon reads a lexicon file.
+// See ReadLexicon for details on the format.
+func LoadLexicon(path string) (Lexicon, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, essentials.AddCtx("load lexicon", err)
+	}
+	defer f.Close()
+	return ReadLexicon(f)
+}
+
+// ReadLexicon reads a lexicon with one word per line,
+// followed by its related words, all separated by
+// whitespace.
+//
+// If a word appears at the start of multiple lines, its
+// related words are combined.
+// Blank lines are ignored.
+func ReadLexicon(r io.Reader) (Lexicon, error) {
+	res := Lexicon{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<24)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		res.Add(fields[0], fields[1:]...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, essentials.AddCtx("read lexicon", err)
+	}
+	return res, nil
+}
+
+// Add adds edges from a word to related words.
+//
+// Duplicate edges and edges from a word to itself are
+// ignored.
+func (l Lexicon) Add(word string, related ...string) {
+	neighbors := l[word]
+	for _, r := range related {
+		if r != word && !containsString(neighbors, r) {
+			neighbors = append(neighbors, r)
+		}
+	}
+	l[word] = neighbors
+}
+
+// Symmetrize creates a copy of the lexicon in which every
+// edge also goes in the opposite direction.
+func (l Lexicon) Symmetrize() Lexicon {
+	var words []string
+	for word := range l {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	res := Lexicon{}
+	for _, word := range words {
+		res.Add(word, l[word]...)
+		for _, related := range l[word] {
+			res.Add(related, word)
+		}
+	}
+	return res
+}
+
+func containsString(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+// Package retrofit refines word embeddings using semantic
+// lexicons, as described in Faruqui et al., "Retrofitting
+// Word Vectors to Semantic Lexicons".
+package retrofit
+
+import (
+	"sort"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/wordembed"
+	"github.com/unixpickle/wordembed/glove"
+)
+
+const (
+	defaultAlpha      = 1
+	defaultIterations = 10
+)
+
+// A Retrofitter pulls word vectors towards the vectors of
+// related words, while keeping them close to their
+// original values.
+//
+// Each iteration updates every word with related words in
+// the vocabulary to
+//
+//	(Alpha*original + sum(beta*neighbor)) / (Alpha + sum(beta))
+//
+// where the sums are over the word's related words.
+// The updates are applied in place, in sorted order, so
+// later words see the updated vectors of earlier words.
+type Retrofitter struct {
+	// Alpha weights a word's original vector.
+	//
+	// If this is 0, 1 is used.
+	Alpha float64
+
+	// Beta weights each related word's vector.
+	//
+	// If this is 0, the weight for each related word is one
+	// over the number of related words, as in the paper.
+	Beta float64
+
+	// Iterations is the number of updates to apply to each
+	// vector.
+	//
+	// If this is 0, 10 is used.
+	Iterations int
+}
+
+// Retrofit produces a retrofitted copy of an embedding.
+//
+// Words in the lexicon which are not in the embedding are
+// ignored.
+// Words without related words in the embedding, including
+// the unknown token, keep their original vectors.
+func (r *Retrofitter) Retrofit(e wordembed.Embedding, lexicon Lexicon) *glove.Embedding {
+	alpha := r.Alpha
+	if alpha == 0 {
+		alpha = defaultAlpha
+	}
+	iterations := r.Iterations
+	if iterations == 0 {
+		iterations = defaultIterations
+	}
+
+	tokens := wordembed.TokenSet(append([]string{}, wordembed.EmbeddingTokens(e)...))
+	sort.Strings(tokens)
+	original := make([][]float64, tokens.NumIDs())
+	for id, token := range tokens {
+		original[id] = wordembed.NumericListFloat64(e.Embed(token).Data())
+	}
+	original[len(tokens)] = wordembed.NumericListFloat64(e.EmbedID(len(tokens)).Data())
+
+	neighbors := make([][]int, len(tokens))
+	for id, token := range tokens {
+		for _, related := range lexicon[token] {
+			if relatedID := tokens.ID(related); relatedID != len(tokens) && relatedID != id {
+				neighbors[id] = append(neighbors[id], relatedID)
+			}
+		}
+	}
+
+	vecs := make([][]float64, len(original))
+	for id, vec := range original {
+		vecs[id] = append([]float64{}, vec...)
+	}
+	for i := 0; i < iterations; i++ {
+		for id, ids := range neighbors {
+			if len(ids) == 0 {
+				continue
+			}
+			beta := r.Beta
+			if beta == 0 {
+				beta = 1 / float64(len(ids))
+			}
+			vec := vecs[id]
+			for j, x := range original[id] {
+				vec[j] = alpha * x
+			}
+			for _, neighbor := range ids {
+				for j, x := range vecs[neighbor] {
+					vec[j] += beta * x
+				}
+			}
+			norm := 1 / (alpha + beta*float64(len(ids)))
+			for j := range vec {
+				vec[j] *= norm
+			}
+		}
+	}
+
+	c := e.EmbedID(0).Creator()
+	var data []float64
+	for _, vec := range vecs {
+		data = append(data, vec...)
+	}
+	return &glove.Embedding{
+		Tokens: tokens,
+		Vectors: &anyvec.Matrix{
+			Data: c.MakeVectorData(c.MakeNumericList(data)),
+			Rows: len(vecs),
+			Cols: e.Dim(),
+		},
+	}
+}
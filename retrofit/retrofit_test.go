@@ -0,0 +1,89 @@
+package retrofit
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvec32"
+	"github.com/unixpickle/wordembed"
+	"github.com/unixpickle/wordembed/glove"
+)
+
+func TestReadLexicon(t *testing.T) {
+	lexicon, err := ReadLexicon(strings.NewReader("car auto automobile car\n\n" +
+		"auto car\ncar vehicle auto\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Lexicon{
+		"car":  {"auto", "automobile", "vehicle"},
+		"auto": {"car"},
+	}
+	if !reflect.DeepEqual(lexicon, expected) {
+		t.Errorf("expected %v but got %v", expected, lexicon)
+	}
+	expected = Lexicon{
+		"car":        {"auto", "automobile", "vehicle"},
+		"auto":       {"car"},
+		"automobile": {"car"},
+		"vehicle":    {"car"},
+	}
+	if actual := lexicon.Symmetrize(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v but got %v", expected, actual)
+	}
+}
+
+func TestRetrofit(t *testing.T) {
+	e := testEmbedding()
+	lexicon := Lexicon{"a": {"b", "missing"}, "b": {"a"}}
+	r := &Retrofitter{Iterations: 100}
+	res := r.Retrofit(e, lexicon)
+
+	// At convergence, a = (a0 + b) / 2 and b = (b0 + a) / 2.
+	a0 := []float64{1, 0}
+	b0 := []float64{0, 1}
+	expected := map[string][]float64{
+		"a": {(2*a0[0] + b0[0]) / 3, (2*a0[1] + b0[1]) / 3},
+		"b": {(2*b0[0] + a0[0]) / 3, (2*b0[1] + a0[1]) / 3},
+		"c": {3, 3},
+		"":  {-1, -1},
+	}
+	for token, vec := range expected {
+		actual := res.Embed(token).Data().([]float32)
+		for i, x := range vec {
+			if math.Abs(x-float64(actual[i])) > 1e-5 {
+				t.Errorf("token %q: expected %v but got %v", token, vec, actual)
+				break
+			}
+		}
+	}
+	if !reflect.DeepEqual(res.Tokens, e.Tokens) {
+		t.Errorf("unexpected tokens: %v", res.Tokens)
+	}
+
+	// A large alpha keeps vectors near their originals.
+	r = &Retrofitter{Alpha: 1000, Beta: 1}
+	actual := r.Retrofit(e, lexicon).Embed("a").Data().([]float32)
+	if math.Abs(float64(actual[0])-1) > 1e-2 || math.Abs(float64(actual[1])) > 1e-2 {
+		t.Errorf("vector moved too far: %v", actual)
+	}
+}
+
+func testEmbedding() *glove.Embedding {
+	return &glove.Embedding{
+		Tokens: wordembed.TokenSet{"a", "b", "c"},
+		Vectors: &anyvec.Matrix{
+			Data: anyvec32.MakeVectorData([]float32{
+				1, 0,
+				0, 1,
+				3, 3,
+				-1, -1,
+			}),
+			Rows: 4,
+			Cols: 2,
+		},
+	}
+}
@@ -0,0 +1,242 @@
+package wordembed
+
+import (
+	"container/heap"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/unixpickle/anyvec"
+)
+
+// normEpsilon is added to squared norms so that zero
+// vectors stay zero when they are normalized.
+const normEpsilon = 1e-20
+
+// A CosineSearcher performs exact cosine similarity
+// searches over the rows of a matrix.
+//
+// The rows are normalized once when the CosineSearcher is
+// created, so that each query only requires a single
+// matrix-vector product.
+type CosineSearcher struct {
+	// Normalized contains a copy of the searched matrix in
+	// which every row has unit magnitude.
+	// Zero rows remain zero.
+	Normalized *anyvec.Matrix
+}
+
+// NewCosineSearcher creates a CosineSearcher for the rows
+// of a matrix.
+//
+// The matrix is copied, so later changes to it do not
+// affect the CosineSearcher.
+func NewCosineSearcher(mat *anyvec.Matrix) *CosineSearcher {
+	return &CosineSearcher{
+		Normalized: &anyvec.Matrix{
+			Data: normalizeRows(mat.Data, mat.Rows),
+			Rows: mat.Rows,
+			Cols: mat.Cols,
+		},
+	}
+}
+
+// Similarities computes the cosine similarity between the
+// vector and every row.
+func (c *CosineSearcher) Similarities(vec anyvec.Vector) anyvec.Vector {
+	if vec.Len() != c.Normalized.Cols {
+		panic("incorrect vector length")
+	}
+	return c.BatchSimilarities(&anyvec.Matrix{Data: vec, Rows: 1, Cols: vec.Len()}).Data
+}
+
+// BatchSimilarities computes the cosine similarity
+// between every query row and every searched row.
+//
+// The result has one row per query and one column per
+// searched row.
+// It is computed with a single matrix-matrix product.
+func (c *CosineSearcher) BatchSimilarities(queries *anyvec.Matrix) *anyvec.Matrix {
+	if queries.Cols != c.Normalized.Cols {
+		panic("incorrect vector length")
+	}
+	cr := queries.Data.Creator()
+	res := &anyvec.Matrix{
+		Data: cr.MakeVector(queries.Rows * c.Normalized.Rows),
+		Rows: queries.Rows,
+		Cols: c.Normalized.Rows,
+	}
+	normQueries := &anyvec.Matrix{
+		Data: normalizeRows(queries.Data, queries.Rows),
+		Rows: queries.Rows,
+		Cols: queries.Cols,
+	}
+	res.Product(false, true, cr.MakeNumeric(1), normQueries, c.Normalized, cr.MakeNumeric(0))
+	return res
+}
+
+// Lookup finds the n rows with the highest cosine
+// similarity to the vector.
+// For each row index, it also returns the similarity.
+//
+// If n is greater than the number of rows, then there
+// will be fewer than n results.
+func (c *CosineSearcher) Lookup(vec anyvec.Vector, n int) ([]int, []anyvec.Numeric) {
+	return TopK(c.Similarities(vec), n)
+}
+
+// LookupBatch is like Lookup, but it answers a query for
+// each row of a matrix.
+//
+// The similarities for all the queries are computed at
+// once, so the memory usage is proportional to the number
+// of queries times the number of rows.
+func (c *CosineSearcher) LookupBatch(queries *anyvec.Matrix,
+	n int) ([][]int, [][]anyvec.Numeric) {
+	sims := c.BatchSimilarities(queries)
+	ids := make([][]int, queries.Rows)
+	values := make([][]anyvec.Numeric, queries.Rows)
+	for i := range ids {
+		row := sims.Data.Slice(i*sims.Cols, (i+1)*sims.Cols)
+		ids[i], values[i] = TopK(row, n)
+	}
+	return ids, values
+}
+
+// A SearcherCache lazily creates a CosineSearcher for a
+// matrix and reuses it until the matrix is replaced.
+//
+// It is safe to use a SearcherCache from multiple
+// Goroutines at once.
+type SearcherCache struct {
+	lock     sync.Mutex
+	mat      anyvec.Matrix
+	searcher *CosineSearcher
+}
+
+// Searcher returns the cached CosineSearcher, creating a
+// new one if the cache is empty or if the matrix (or its
+// underlying vector) has changed since the last call.
+func (s *SearcherCache) Searcher(mat *anyvec.Matrix) *CosineSearcher {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.searcher == nil || s.mat != *mat {
+		s.searcher = NewCosineSearcher(mat)
+		s.mat = *mat
+	}
+	return s.searcher
+}
+
+// Clear discards the cached CosineSearcher.
+//
+// This must be called if the data in the matrix is
+// modified in place.
+func (s *SearcherCache) Clear() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.searcher = nil
+	s.mat = anyvec.Matrix{}
+}
+
+// LoadSearcherCache returns the SearcherCache stored at p,
+// atomically storing a new one first if *p is nil.
+//
+// This lets embeddings create their caches lazily without
+// sharing a lock.
+func LoadSearcherCache(p **SearcherCache) *SearcherCache {
+	ptr := (*unsafe.Pointer)(unsafe.Pointer(p))
+	if cache := atomic.LoadPointer(ptr); cache != nil {
+		return (*SearcherCache)(cache)
+	}
+	atomic.CompareAndSwapPointer(ptr, nil, unsafe.Pointer(&SearcherCache{}))
+	return (*SearcherCache)(atomic.LoadPointer(ptr))
+}
+
+// TopK finds the indices of the k largest components of a
+// vector, sorted from largest to smallest.
+// For each index, it also returns the component.
+//
+// Ties are broken in favor of smaller indices.
+// If k is greater than the length of the vector, then
+// there will be fewer than k results.
+func TopK(vec anyvec.Vector, k int) ([]int, []anyvec.Numeric) {
+	values := NumericListFloat64(vec.Data())
+
+	if k > len(values) {
+		k = len(values)
+	}
+	if k <= 0 {
+		return nil, nil
+	}
+	h := &topKHeap{Values: values}
+	for i, x := range values {
+		if len(h.Indices) < k {
+			heap.Push(h, i)
+		} else if x > values[h.Indices[0]] {
+			h.Indices[0] = i
+			heap.Fix(h, 0)
+		}
+	}
+
+	ids := h.Indices
+	sort.Slice(ids, func(i, j int) bool {
+		return h.better(ids[i], ids[j])
+	})
+	c := vec.Creator()
+	res := make([]anyvec.Numeric, len(ids))
+	for i, idx := range ids {
+		res[i] = c.MakeNumeric(values[idx])
+	}
+	return ids, res
+}
+
+// normalizeRows produces a copy of a row-major matrix in
+// which every row has unit magnitude.
+func normalizeRows(data anyvec.Vector, rows int) anyvec.Vector {
+	c := data.Creator()
+	squares := data.Copy()
+	anyvec.Pow(squares, c.MakeNumeric(2))
+	normalizers := anyvec.SumCols(squares, rows)
+	normalizers.AddScalar(c.MakeNumeric(normEpsilon))
+	anyvec.Pow(normalizers, c.MakeNumeric(-0.5))
+	res := data.Copy()
+	anyvec.ScaleChunks(res, normalizers)
+	return res
+}
+
+// topKHeap is a min-heap of indices, ordered by the value
+// at each index.
+type topKHeap struct {
+	Values  []float64
+	Indices []int
+}
+
+func (t *topKHeap) Len() int {
+	return len(t.Indices)
+}
+
+func (t *topKHeap) Less(i, j int) bool {
+	return t.better(t.Indices[j], t.Indices[i])
+}
+
+func (t *topKHeap) Swap(i, j int) {
+	t.Indices[i], t.Indices[j] = t.Indices[j], t.Indices[i]
+}
+
+func (t *topKHeap) Push(x interface{}) {
+	t.Indices = append(t.Indices, x.(int))
+}
+
+func (t *topKHeap) Pop() interface{} {
+	res := t.Indices[len(t.Indices)-1]
+	t.Indices = t.Indices[:len(t.Indices)-1]
+	return res
+}
+
+func (t *topKHeap) better(idx1, idx2 int) bool {
+	if t.Values[idx1] == t.Values[idx2] {
+		return idx1 < idx2
+	}
+	return t.Values[idx1] > t.Values[idx2]
+}
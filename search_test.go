@@ -0,0 +1,95 @@
+package wordembed
+
+import (
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvec32"
+)
+
+func TestTopK(t *testing.T) {
+	vec := anyvec32.MakeVectorData([]float32{3, -1, 7, 3, 0, 5})
+	ids, values := TopK(vec, 4)
+	if !reflect.DeepEqual(ids, []int{2, 5, 0, 3}) {
+		t.Errorf("unexpected IDs: %v", ids)
+	}
+	expected := []anyvec.Numeric{float32(7), float32(5), float32(3), float32(3)}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("unexpected values: %v", values)
+	}
+	if ids, _ := TopK(vec, 10); len(ids) != 6 {
+		t.Errorf("expected 6 results but got %d", len(ids))
+	}
+}
+
+func TestCosineSearcher(t *testing.T) {
+	mat := &anyvec.Matrix{
+		Data: anyvec32.MakeVectorData([]float32{
+			1, 1,
+			0, 0,
+			0, 2,
+			-3, 0,
+		}),
+		Rows: 4,
+		Cols: 2,
+	}
+	searcher := NewCosineSearcher(mat)
+	ids, sims := searcher.Lookup(anyvec32.MakeVectorData([]float32{0, 5}), 4)
+	if !reflect.DeepEqual(ids, []int{2, 0, 1, 3}) {
+		t.Errorf("unexpected IDs: %v", ids)
+	}
+	expected := []float64{1, math.Sqrt(0.5), 0, 0}
+	for i, x := range expected {
+		if math.Abs(float64(sims[i].(float32))-x) > 1e-5 {
+			t.Errorf("expected similarities %v but got %v", expected, sims)
+			break
+		}
+	}
+}
+
+func TestCosineSearcherBatch(t *testing.T) {
+	mat := &anyvec.Matrix{Data: anyvec32.MakeVector(50 * 8), Rows: 50, Cols: 8}
+	anyvec.Rand(mat.Data, anyvec.Normal, nil)
+	searcher := NewCosineSearcher(mat)
+	queries := &anyvec.Matrix{Data: anyvec32.MakeVector(5 * 8), Rows: 5, Cols: 8}
+	anyvec.Rand(queries.Data, anyvec.Normal, nil)
+
+	ids, sims := searcher.LookupBatch(queries, 3)
+	for i := 0; i < queries.Rows; i++ {
+		expectedIDs, expectedSims := searcher.Lookup(queries.Data.Slice(i*8, (i+1)*8), 3)
+		if !reflect.DeepEqual(ids[i], expectedIDs) {
+			t.Errorf("query %d: expected %v but got %v", i, expectedIDs, ids[i])
+		}
+		for j, sim := range sims[i] {
+			if math.Abs(float64(sim.(float32)-expectedSims[j].(float32))) > 1e-5 {
+				t.Errorf("query %d: expected %v but got %v", i, expectedSims, sims[i])
+				break
+			}
+		}
+	}
+}
+
+func TestLoadSearcherCache(t *testing.T) {
+	var cache *SearcherCache
+	results := make([]*SearcherCache, 8)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = LoadSearcherCache(&cache)
+		}(i)
+	}
+	wg.Wait()
+	for i, res := range results {
+		if res == nil || res != results[0] {
+			t.Fatalf("result %d: got a different cache", i)
+		}
+	}
+	if LoadSearcherCache(&cache) != results[0] {
+		t.Error("cache was replaced")
+	}
+}
@@ -0,0 +1,119 @@
+// Package sentence builds sentence and document vectors
+// from word embeddings.
+package sentence
+
+import (
+	"sync"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/wordembed"
+)
+
+// A Pooling combines word vectors into a sentence vector.
+type Pooling interface {
+	// Pool combines the vectors for the in-vocabulary
+	// tokens of a sentence.
+	// The vectors correspond to the tokens, and there is
+	// at least one of each.
+	Pool(tokens []string, vecs []anyvec.Vector) anyvec.Vector
+}
+
+// An Encoder produces sentence vectors by pooling the
+// vectors of the words in each sentence.
+type Encoder struct {
+	Embedding wordembed.Embedding
+	Pooling   Pooling
+
+	vocab vocabCache
+}
+
+// NewEncoder creates an Encoder.
+func NewEncoder(e wordembed.Embedding, p Pooling) *Encoder {
+	return &Encoder{Embedding: e, Pooling: p}
+}
+
+// Encode produces a vector for a tokenized sentence.
+//
+// Tokens which are not in the embedding's vocabulary are
+// ignored.
+// If there are no other tokens, a zero vector is returned.
+func (e *Encoder) Encode(sentence []string) anyvec.Vector {
+	vocab := e.vocab.IDs(e.Embedding)
+	var tokens []string
+	var vecs []anyvec.Vector
+	for _, token := range sentence {
+		if id, ok := vocab[token]; ok {
+			tokens = append(tokens, token)
+			vecs = append(vecs, e.Embedding.EmbedID(id))
+		}
+	}
+	if len(tokens) == 0 {
+		return e.Embedding.EmbedID(0).Creator().MakeVector(e.Embedding.Dim())
+	}
+	return e.Pooling.Pool(tokens, vecs)
+}
+
+// EncodeAll encodes every sentence in a list.
+func (e *Encoder) EncodeAll(sentences [][]string) []anyvec.Vector {
+	res := make([]anyvec.Vector, len(sentences))
+	for i, sentence := range sentences {
+		res[i] = e.Encode(sentence)
+	}
+	return res
+}
+
+// Mean is a Pooling which averages the word vectors.
+type Mean struct{}
+
+// Pool averages the vectors.
+func (m Mean) Pool(tokens []string, vecs []anyvec.Vector) anyvec.Vector {
+	weights := make([]float64, len(vecs))
+	for i := range weights {
+		weights[i] = 1
+	}
+	return weightedMean(vecs, weights)
+}
+
+// weightedMean computes the weighted average of vectors.
+func weightedMean(vecs []anyvec.Vector, weights []float64) anyvec.Vector {
+	res := weightedSum(vecs, weights)
+	var total float64
+	for _, weight := range weights {
+		total += weight
+	}
+	if total != 0 {
+		res.Scale(res.Creator().MakeNumeric(1 / total))
+	}
+	return res
+}
+
+// weightedSum computes the weighted sum of vectors.
+func weightedSum(vecs []anyvec.Vector, weights []float64) anyvec.Vector {
+	c := vecs[0].Creator()
+	res := c.MakeVector(vecs[0].Len())
+	for i, vec := range vecs {
+		scaled := vec.Copy()
+		scaled.Scale(c.MakeNumeric(weights[i]))
+		res.Add(scaled)
+	}
+	return res
+}
+
+// vocabCache lazily maps the tokens of an embedding to
+// their IDs.
+type vocabCache struct {
+	once sync.Once
+	ids  map[string]int
+}
+
+// IDs returns the mapping for the embedding.
+// The embedding must be the same on every call.
+func (v *vocabCache) IDs(e wordembed.Embedding) map[string]int {
+	v.once.Do(func() {
+		v.ids = map[string]int{}
+		for id, token := range wordembed.EmbeddingTokens(e) {
+			v.ids[token] = id
+		}
+	})
+	return v.ids
+}
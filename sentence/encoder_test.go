@@ -0,0 +1,59 @@
+package sentence
+
+import (
+	"math"
+	"testing"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvec32"
+	"github.com/unixpickle/wordembed"
+	"github.com/unixpickle/wordembed/glove"
+)
+
+func TestEncoderMean(t *testing.T) {
+	enc := NewEncoder(testEmbedding(), Mean{})
+	actual := enc.Encode([]string{"a", "unknown", "b", "b"}).Data().([]float32)
+	assertClose(t, actual, []float32{1.0 / 3, 2.0 / 3, 0})
+
+	actual = enc.Encode([]string{"unknown"}).Data().([]float32)
+	assertClose(t, actual, []float32{0, 0, 0})
+}
+
+func TestEncoderTFIDF(t *testing.T) {
+	tfidf := NewTFIDF([][]string{{"a", "b"}, {"b", "c"}, {"b"}})
+	if math.Abs(tfidf.IDF("b")-1) > 1e-8 {
+		t.Errorf("unexpected IDF: %f", tfidf.IDF("b"))
+	}
+	idfA := math.Log(2) + 1
+	enc := NewEncoder(testEmbedding(), tfidf)
+	actual := enc.Encode([]string{"a", "b"}).Data().([]float32)
+	total := idfA + 1
+	assertClose(t, actual, []float32{float32(idfA / total), float32(1 / total), 0})
+}
+
+func testEmbedding() *glove.Embedding {
+	tokens := wordembed.TokenSet{"a", "b", "c", "d"}
+	return &glove.Embedding{
+		Tokens: tokens,
+		Vectors: &anyvec.Matrix{
+			Data: anyvec32.MakeVectorData([]float32{
+				1, 0, 0,
+				0, 1, 0,
+				0, 0, 1,
+				1, 1, 1,
+				5, 5, 5,
+			}),
+			Rows: tokens.NumIDs(),
+			Cols: 3,
+		},
+	}
+}
+
+func assertClose(t *testing.T, actual, expected []float32) {
+	for i, x := range expected {
+		if math.Abs(float64(actual[i]-x)) > 1e-5 {
+			t.Errorf("expected %v but got %v", expected, actual)
+			return
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package sentence
+
+import (
+	"encoding/json"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvecsave"
+	"github.com/unixpickle/essentials"
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/wordembed"
+)
+
+func init() {
+	var s SIF
+	serializer.RegisterTypedDeserializer(s.SerializerType(), DeserializeSIF)
+}
+
+const (
+	defaultSIFParam = 1e-3
+
+	powerIterations = 100
+)
+
+// SIF is a Pooling which implements Smooth Inverse
+// Frequency weighting from Arora et al., "A Simple but
+// Tough-to-Beat Baseline for Sentence Embeddings".
+//
+// A word with frequency p is weighted by a/(a+p), and
+// the weighted sum is divided by the number of words, as
+// in the paper (rather than by the sum of the weights).
+// The projection onto Component is then removed from the
+// result.
+type SIF struct {
+	// Counts stores the number of occurrences of each word.
+	Counts wordembed.TokenCounts
+
+	// Total is the sum of all the counts.
+	Total int
+
+	// Param is the parameter a in the weighting formula.
+	//
+	// If this is 0, 1e-3 is used.
+	Param float64
+
+	// Component is a unit vector, usually the first
+	// principal component of a set of sentence vectors.
+	// See Fit.
+	//
+	// If this is nil, no component is removed.
+	Component anyvec.Vector
+}
+
+// DeserializeSIF deserializes a SIF.
+func DeserializeSIF(d []byte) (*SIF, error) {
+	var counts serializer.Bytes
+	var total serializer.Int
+	var param float64
+	var component serializer.Serializer
+	if err := serializer.DeserializeAny(d, &counts, &total, &param, &component); err != nil {
+		return nil, essentials.AddCtx("deserialize SIF", err)
+	}
+	res := &SIF{Total: int(total), Param: param}
+	if err := json.Unmarshal(counts, &res.Counts); err != nil {
+		return nil, essentials.AddCtx("deserialize SIF", err)
+	}
+	if vec, ok := component.(*anyvecsave.S); ok {
+		res.Component = vec.Vector
+	}
+	return res, nil
+}
+
+// NewSIF creates a SIF from token counts.
+//
+// The result has no Component until Fit is called.
+func NewSIF(counts wordembed.TokenCounts) *SIF {
+	var total int
+	for _, count := range counts {
+		total += count
+	}
+	return &SIF{Counts: counts, Total: total}
+}
+
+// Weight computes the weight of a word.
+func (s *SIF) Weight(token string) float64 {
+	param := s.Param
+	if param == 0 {
+		param = defaultSIFParam
+	}
+	var freq float64
+	if s.Total > 0 {
+		freq = float64(s.Counts[token]) / float64(s.Total)
+	}
+	return param / (param + freq)
+}
+
+// Fit sets Component to the first principal component of
+// the unprojected sentence vectors for a reference corpus.
+//
+// As in the original SIF implementation, the vectors are
+// not centered before the component is computed.
+func (s *SIF) Fit(e wordembed.Embedding, corpus [][]string) {
+	unprojected := *s
+	unprojected.Component = nil
+	vecs := NewEncoder(e, &unprojected).EncodeAll(corpus)
+	s.Component = nil
+	if len(vecs) > 0 {
+		s.Component = firstComponent(vecs)
+	}
+}
+
+// Pool computes the weighted sum of the vectors, divides
+// it by the number of vectors, and removes the projection
+// onto Component.
+func (s *SIF) Pool(tokens []string, vecs []anyvec.Vector) anyvec.Vector {
+	weights := make([]float64, len(tokens))
+	for i, token := range tokens {
+		weights[i] = s.Weight(token)
+	}
+	res := weightedSum(vecs, weights)
+	res.Scale(res.Creator().MakeNumeric(1 / float64(len(vecs))))
+	if s.Component != nil {
+		c := res.Creator()
+		projection := s.Component.Copy()
+		projection.Scale(c.NumOps().Mul(res.Dot(s.Component), c.MakeNumeric(-1)))
+		res.Add(projection)
+	}
+	return res
+}
+
+// SerializerType returns the unique ID used to serialize
+// a SIF with the serializer package.
+func (s *SIF) SerializerType() string {
+	return "github.com/unixpickle/wordembed/sentence.SIF"
+}
+
+// Serialize serializes the SIF.
+func (s *SIF) Serialize() ([]byte, error) {
+	counts, err := json.Marshal(s.Counts)
+	if err != nil {
+		return nil, err
+	}
+	var component serializer.Serializer = serializer.Bytes(nil)
+	if s.Component != nil {
+		component = &anyvecsave.S{Vector: s.Component}
+	}
+	return serializer.SerializeAny(
+		serializer.Bytes(counts),
+		serializer.Int(s.Total),
+		s.Param,
+		component,
+	)
+}
+
+// firstComponent computes the dominant right singular
+// vector of the matrix whose rows are vecs, using power
+// iteration on the Gram matrix.
+//
+// If all the vectors are zero, nil is returned.
+func firstComponent(vecs []anyvec.Vector) anyvec.Vector {
+	c := vecs[0].Creator()
+	dim := vecs[0].Len()
+	data := &anyvec.Matrix{Data: c.Concat(vecs...), Rows: len(vecs), Cols: dim}
+	gram := &anyvec.Matrix{Data: c.MakeVector(dim * dim), Rows: dim, Cols: dim}
+	gram.Product(true, false, c.MakeNumeric(1), data, data, c.MakeNumeric(0))
+
+	vec := &anyvec.Matrix{Data: c.MakeVector(dim), Rows: dim, Cols: 1}
+	anyvec.Rand(vec.Data, anyvec.Normal, nil)
+	next := &anyvec.Matrix{Data: c.MakeVector(dim), Rows: dim, Cols: 1}
+	for i := 0; i < powerIterations; i++ {
+		next.Product(false, false, c.MakeNumeric(1), gram, vec, c.MakeNumeric(0))
+		norm := anyvec.Norm(next.Data)
+		if norm == c.MakeNumeric(0) {
+			return nil
+		}
+		next.Data.Scale(c.NumOps().Div(c.MakeNumeric(1), norm))
+		vec, next = next, vec
+	}
+	return vec.Data
+}
@@ -0,0 +1,69 @@
+package sentence
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/wordembed"
+)
+
+func TestSIFWeight(t *testing.T) {
+	sif := NewSIF(wordembed.TokenCounts{"a": 999, "b": 1})
+	if w := sif.Weight("a"); math.Abs(w-1e-3/(1e-3+0.999)) > 1e-8 {
+		t.Errorf("unexpected weight: %f", w)
+	}
+	if w := sif.Weight("c"); w != 1 {
+		t.Errorf("unexpected weight for unseen word: %f", w)
+	}
+}
+
+func TestSIFPool(t *testing.T) {
+	sif := NewSIF(wordembed.TokenCounts{"a": 1, "b": 3})
+	sif.Param = 1
+	actual := NewEncoder(testEmbedding(), sif).Encode([]string{"a", "b"})
+
+	// The weights are 0.8 and 4/7, and the weighted sum is
+	// divided by the length of the sentence.
+	assertClose(t, actual.Data().([]float32), []float32{0.4, 2.0 / 7, 0})
+}
+
+func TestSIFFit(t *testing.T) {
+	e := testEmbedding()
+	sif := NewSIF(wordembed.TokenCounts{"a": 2, "b": 1, "c": 1, "d": 1})
+	corpus := [][]string{{"a", "d"}, {"b", "d"}, {"c", "d"}, {"a", "b", "d"}}
+	sif.Fit(e, corpus)
+	if sif.Component == nil {
+		t.Fatal("missing component")
+	}
+	if norm := float64(anyvec.Norm(sif.Component).(float32)); math.Abs(norm-1) > 1e-4 {
+		t.Errorf("component should be a unit vector, but has norm %f", norm)
+	}
+	enc := NewEncoder(e, sif)
+	for _, vec := range enc.EncodeAll(corpus) {
+		if dot := vec.Dot(sif.Component).(float32); math.Abs(float64(dot)) > 1e-4 {
+			t.Errorf("projection should be removed, but got %f", dot)
+		}
+	}
+}
+
+func TestSIFSerialize(t *testing.T) {
+	sif := NewSIF(wordembed.TokenCounts{"a": 2, "b": 1, "c": 1})
+	sif.Param = 0.01
+	for i := 0; i < 2; i++ {
+		data, err := serializer.SerializeAny(sif)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sif1 *SIF
+		if err := serializer.DeserializeAny(data, &sif1); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(sif, sif1) {
+			t.Errorf("iteration %d: expected %v but got %v", i, sif, sif1)
+		}
+		sif.Fit(testEmbedding(), [][]string{{"a", "b"}, {"c"}})
+	}
+}
@@ -0,0 +1,93 @@
+package sentence
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/essentials"
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/wordembed"
+)
+
+func init() {
+	var t TFIDF
+	serializer.RegisterTypedDeserializer(t.SerializerType(), DeserializeTFIDF)
+}
+
+// TFIDF is a Pooling which averages word vectors weighted
+// by term frequency times inverse document frequency.
+//
+// The inverse document frequency of a word is computed as
+// log((1+N)/(1+df)) + 1, where N is the number of
+// documents and df is the number of documents containing
+// the word.
+// Repeated words are weighted once per occurrence, which
+// accounts for the term frequency.
+type TFIDF struct {
+	// DocFreqs stores the number of documents containing
+	// each word.
+	DocFreqs wordembed.TokenCounts
+
+	// NumDocs is the total number of documents.
+	NumDocs int
+}
+
+// DeserializeTFIDF deserializes a TFIDF.
+func DeserializeTFIDF(d []byte) (*TFIDF, error) {
+	var docFreqs serializer.Bytes
+	var numDocs serializer.Int
+	if err := serializer.DeserializeAny(d, &docFreqs, &numDocs); err != nil {
+		return nil, essentials.AddCtx("deserialize TFIDF", err)
+	}
+	res := &TFIDF{NumDocs: int(numDocs)}
+	if err := json.Unmarshal(docFreqs, &res.DocFreqs); err != nil {
+		return nil, essentials.AddCtx("deserialize TFIDF", err)
+	}
+	return res, nil
+}
+
+// NewTFIDF computes document frequencies from a corpus of
+// tokenized documents.
+func NewTFIDF(docs [][]string) *TFIDF {
+	res := &TFIDF{DocFreqs: wordembed.TokenCounts{}, NumDocs: len(docs)}
+	for _, doc := range docs {
+		seen := map[string]bool{}
+		for _, token := range doc {
+			if !seen[token] {
+				seen[token] = true
+				res.DocFreqs.Add(token)
+			}
+		}
+	}
+	return res
+}
+
+// IDF computes the inverse document frequency of a word.
+func (t *TFIDF) IDF(token string) float64 {
+	return math.Log(float64(1+t.NumDocs)/float64(1+t.DocFreqs[token])) + 1
+}
+
+// Pool computes the weighted average of the vectors.
+func (t *TFIDF) Pool(tokens []string, vecs []anyvec.Vector) anyvec.Vector {
+	weights := make([]float64, len(tokens))
+	for i, token := range tokens {
+		weights[i] = t.IDF(token)
+	}
+	return weightedMean(vecs, weights)
+}
+
+// SerializerType returns the unique ID used to serialize
+// a TFIDF with the serializer package.
+func (t *TFIDF) SerializerType() string {
+	return "github.com/unixpickle/wordembed/sentence.TFIDF"
+}
+
+// Serialize serializes the TFIDF.
+func (t *TFIDF) Serialize() ([]byte, error) {
+	docFreqs, err := json.Marshal(t.DocFreqs)
+	if err != nil {
+		return nil, err
+	}
+	return serializer.SerializeAny(serializer.Bytes(docFreqs), serializer.Int(t.NumDocs))
+}
@@ -0,0 +1,331 @@
+package sentence
+
+import (
+	"math"
+	"sort"
+
+	"github.com/unixpickle/wordembed"
+)
+
+// flowEpsilon is the amount of flow which is considered
+// to be zero when solving transport problems.
+const flowEpsilon = 1e-12
+
+// A WMD computes Word Mover's Distances between tokenized
+// documents, as described in Kusner et al., "From Word
+// Embeddings To Document Distances".
+//
+// Each document is represented by its normalized
+// bag-of-words (nBOW) weights, where the weight of a word
+// is its number of occurrences divided by the length of
+// the document.
+// Words which are not in the embedding's vocabulary are
+// ignored.
+// The cost of moving weight between two words is the
+// Euclidean distance between their vectors.
+type WMD struct {
+	Embedding wordembed.Embedding
+
+	vocab vocabCache
+}
+
+// NewWMD creates a WMD for an embedding.
+func NewWMD(e wordembed.Embedding) *WMD {
+	return &WMD{Embedding: e}
+}
+
+// Distance computes the Word Mover's Distance between two
+// documents.
+//
+// The result is the minimum total cost of moving the nBOW
+// weights of one document onto those of the other.
+// If either document has no in-vocabulary words, the
+// result is positive infinity.
+func (w *WMD) Distance(doc1, doc2 []string) float64 {
+	weights1, vecs1 := w.nbow(doc1)
+	weights2, vecs2 := w.nbow(doc2)
+	if len(weights1) == 0 || len(weights2) == 0 {
+		return math.Inf(1)
+	}
+	return transportCost(weights1, weights2, distanceMatrix(vecs1, vecs2))
+}
+
+// RelaxedDistance computes the relaxed Word Mover's
+// Distance (RWMD), a lower bound on Distance which is much
+// cheaper to compute.
+//
+// For each document, every word moves all of its weight
+// to the closest word in the other document.
+// The result is the larger of the two resulting costs.
+// If either document has no in-vocabulary words, the
+// result is positive infinity.
+//
+// See Nearest for an example of using the bound to prune
+// distance computations.
+func (w *WMD) RelaxedDistance(doc1, doc2 []string) float64 {
+	weights1, vecs1 := w.nbow(doc1)
+	weights2, vecs2 := w.nbow(doc2)
+	if len(weights1) == 0 || len(weights2) == 0 {
+		return math.Inf(1)
+	}
+	return relaxedCost(weights1, weights2, distanceMatrix(vecs1, vecs2))
+}
+
+// Nearest finds the k documents with the smallest Word
+// Mover's Distance to a query, sorted from nearest to
+// farthest.
+// It returns the indices of the documents and their
+// distances.
+//
+// As in Kusner et al., the documents are sorted by
+// RelaxedDistance, and the exact distance is only
+// computed for documents whose relaxed distance is less
+// than the k-th smallest distance found so far.
+func (w *WMD) Nearest(query []string, docs [][]string, k int) ([]int, []float64) {
+	type candidate struct {
+		index int
+		dist  float64
+	}
+	weights, vecs := w.nbow(query)
+	candidates := make([]candidate, len(docs))
+	for i, doc := range docs {
+		candidates[i] = candidate{index: i, dist: math.Inf(1)}
+		if docWeights, docVecs := w.nbow(doc); len(weights) > 0 && len(docWeights) > 0 {
+			costs := distanceMatrix(vecs, docVecs)
+			candidates[i].dist = relaxedCost(weights, docWeights, costs)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].dist < candidates[j].dist
+	})
+
+	var best []candidate
+	for _, c := range candidates {
+		if len(best) >= k && (k <= 0 || c.dist >= best[k-1].dist) {
+			break
+		}
+		if !math.IsInf(c.dist, 1) {
+			docWeights, docVecs := w.nbow(docs[c.index])
+			c.dist = transportCost(weights, docWeights, distanceMatrix(vecs, docVecs))
+		}
+		best = append(best, c)
+		sort.SliceStable(best, func(i, j int) bool {
+			return best[i].dist < best[j].dist
+		})
+		if len(best) > k {
+			best = best[:k]
+		}
+	}
+
+	indices := make([]int, len(best))
+	dists := make([]float64, len(best))
+	for i, c := range best {
+		indices[i] = c.index
+		dists[i] = c.dist
+	}
+	return indices, dists
+}
+
+// nbow computes the nBOW weights and vectors for the
+// distinct in-vocabulary words of a document.
+func (w *WMD) nbow(doc []string) ([]float64, [][]float64) {
+	vocab := w.vocab.IDs(w.Embedding)
+	counts := map[string]int{}
+	var total int
+	for _, token := range doc {
+		if _, ok := vocab[token]; ok {
+			counts[token]++
+			total++
+		}
+	}
+	var tokens []string
+	for token := range counts {
+		tokens = append(tokens, token)
+	}
+	sort.Strings(tokens)
+
+	weights := make([]float64, len(tokens))
+	vecs := make([][]float64, len(tokens))
+	for i, token := range tokens {
+		weights[i] = float64(counts[token]) / float64(total)
+		vecs[i] = wordembed.NumericListFloat64(w.Embedding.EmbedID(vocab[token]).Data())
+	}
+	return weights, vecs
+}
+
+// relaxedCost computes the relaxed transport cost for the
+// RWMD, given the weights of both documents and the cost
+// of moving between every pair of their words.
+func relaxedCost(weights1, weights2 []float64, costs [][]float64) float64 {
+	var cost1, cost2 float64
+	for i, weight := range weights1 {
+		minCost := math.Inf(1)
+		for j := range weights2 {
+			minCost = math.Min(minCost, costs[i][j])
+		}
+		cost1 += weight * minCost
+	}
+	for j, weight := range weights2 {
+		minCost := math.Inf(1)
+		for i := range weights1 {
+			minCost = math.Min(minCost, costs[i][j])
+		}
+		cost2 += weight * minCost
+	}
+	return math.Max(cost1, cost2)
+}
+
+func distanceMatrix(vecs1, vecs2 [][]float64) [][]float64 {
+	res := make([][]float64, len(vecs1))
+	for i, v1 := range vecs1 {
+		res[i] = make([]float64, len(vecs2))
+		for j, v2 := range vecs2 {
+			var sum float64
+			for k, x := range v1 {
+				diff := x - v2[k]
+				sum += diff * diff
+			}
+			res[i][j] = math.Sqrt(sum)
+		}
+	}
+	return res
+}
+
+// transportCost solves the transportation problem between
+// a supply and a demand distribution with the same total.
+//
+// It uses the successive shortest path algorithm for
+// min-cost flow on the residual bipartite graph.
+// Since the costs are non-negative, node potentials keep
+// the reduced costs non-negative, so each augmenting path
+// can be found with Dijkstra's algorithm.
+func transportCost(supply, demand []float64, costs [][]float64) float64 {
+	n, m := len(supply), len(demand)
+	flow := make([][]float64, n)
+	for i := range flow {
+		flow[i] = make([]float64, m)
+	}
+	remSupply := append([]float64{}, supply...)
+	remDemand := append([]float64{}, demand...)
+
+	// Nodes 0 through n-1 are suppliers, nodes n through
+	// n+m-1 are consumers, and node n+m is the sink.
+	// The source is implicit: every supplier with remaining
+	// supply starts with distance 0.
+	numNodes := n + m + 1
+	sink := n + m
+	potential := make([]float64, numNodes)
+	dist := make([]float64, numNodes)
+	prev := make([]int, numNodes)
+	done := make([]bool, numNodes)
+
+	// Every augmentation saturates a supplier, a consumer,
+	// or a residual edge, so this bound is never reached
+	// except through rounding errors.
+	maxPaths := 2 * (n + 1) * (m + 1)
+	for path := 0; path < maxPaths; path++ {
+		for i := range dist {
+			dist[i] = math.Inf(1)
+			prev[i] = -1
+			done[i] = false
+		}
+		for i, s := range remSupply {
+			if s > flowEpsilon {
+				dist[i] = 0
+			}
+		}
+		relax := func(from, to int, cost float64) {
+			// Rounding errors may make reduced costs slightly
+			// negative, which Dijkstra's algorithm cannot handle.
+			reduced := cost + potential[from] - potential[to]
+			if reduced < 0 {
+				reduced = 0
+			}
+			if d := dist[from] + reduced; d < dist[to] {
+				dist[to] = d
+				prev[to] = from
+			}
+		}
+		for {
+			node := -1
+			for i, d := range dist {
+				if !done[i] && (node == -1 || d < dist[node]) {
+					node = i
+				}
+			}
+			if node == -1 || math.IsInf(dist[node], 1) {
+				break
+			}
+			done[node] = true
+			if node == sink {
+				break
+			}
+			if node < n {
+				for j := 0; j < m; j++ {
+					relax(node, n+j, costs[node][j])
+				}
+			} else {
+				j := node - n
+				for i := 0; i < n; i++ {
+					if flow[i][j] > flowEpsilon {
+						relax(node, i, -costs[i][j])
+					}
+				}
+				if remDemand[j] > flowEpsilon {
+					relax(node, sink, 0)
+				}
+			}
+		}
+		if math.IsInf(dist[sink], 1) {
+			break
+		}
+
+		// Nodes which were not finalized are at least as far
+		// as the sink, so capping the distances at the sink's
+		// distance keeps every reduced cost non-negative.
+		for i, d := range dist {
+			potential[i] += math.Min(d, dist[sink])
+		}
+
+		// Find the bottleneck along the path.
+		consumer := prev[sink]
+		amount := remDemand[consumer-n]
+		node := consumer
+		for steps := 0; steps < numNodes; steps++ {
+			from := prev[node]
+			if from == -1 {
+				amount = math.Min(amount, remSupply[node])
+				break
+			}
+			if node < n {
+				amount = math.Min(amount, flow[node][from-n])
+			}
+			node = from
+		}
+
+		// Apply the flow along the path.
+		remDemand[consumer-n] -= amount
+		node = consumer
+		for steps := 0; steps < numNodes; steps++ {
+			from := prev[node]
+			if from == -1 {
+				remSupply[node] -= amount
+				break
+			}
+			if node < n {
+				flow[node][from-n] -= amount
+			} else {
+				flow[from][node-n] += amount
+			}
+			node = from
+		}
+	}
+
+	var total float64
+	for i, row := range flow {
+		for j, f := range row {
+			total += f * costs[i][j]
+		}
+	}
+	return total
+}
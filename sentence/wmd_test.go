@@ -0,0 +1,184 @@
+package sentence
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvec32"
+	"github.com/unixpickle/wordembed"
+	"github.com/unixpickle/wordembed/glove"
+)
+
+func TestWMD(t *testing.T) {
+	wmd := NewWMD(testEmbedding())
+
+	if d := wmd.Distance([]string{"a", "b"}, []string{"b", "a", "unknown"}); d > 1e-8 {
+		t.Errorf("expected zero distance but got %f", d)
+	}
+
+	// Half of the weight stays at b, and the other half
+	// moves from a to c.
+	d := wmd.Distance([]string{"a", "b"}, []string{"b", "c"})
+	if math.Abs(d-math.Sqrt2/2) > 1e-5 {
+		t.Errorf("expected %f but got %f", math.Sqrt2/2, d)
+	}
+	if rd := wmd.RelaxedDistance([]string{"a", "b"}, []string{"b", "c"}); rd > d+1e-8 {
+		t.Errorf("relaxed distance %f exceeds distance %f", rd, d)
+	}
+
+	if d := wmd.Distance([]string{"a"}, []string{"unknown"}); !math.IsInf(d, 1) {
+		t.Errorf("expected infinite distance but got %f", d)
+	}
+}
+
+func TestTransportCost(t *testing.T) {
+	// With uniform weights, the optimal transport plan is
+	// a permutation.
+	for trial := 0; trial < 20; trial++ {
+		costs := [][]float64{
+			{rand.Float64(), rand.Float64(), rand.Float64()},
+			{rand.Float64(), rand.Float64(), rand.Float64()},
+			{rand.Float64(), rand.Float64(), rand.Float64()},
+		}
+		weights := []float64{1.0 / 3, 1.0 / 3, 1.0 / 3}
+		expected := math.Inf(1)
+		for _, perm := range [][]int{{0, 1, 2}, {0, 2, 1}, {1, 0, 2}, {1, 2, 0},
+			{2, 0, 1}, {2, 1, 0}} {
+			var cost float64
+			for i, j := range perm {
+				cost += costs[i][j] / 3
+			}
+			expected = math.Min(expected, cost)
+		}
+		actual := transportCost(weights, weights, costs)
+		if math.Abs(actual-expected) > 1e-8 {
+			t.Fatalf("expected %f but got %f", expected, actual)
+		}
+	}
+}
+
+func TestTransportCostUnbalanced(t *testing.T) {
+	// Splitting each supplier in two turns the problem into
+	// an assignment problem on a 4x4 matrix.
+	for trial := 0; trial < 20; trial++ {
+		costs := make([][]float64, 2)
+		for i := range costs {
+			costs[i] = []float64{rand.Float64(), rand.Float64(), rand.Float64(),
+				rand.Float64()}
+		}
+		expected := math.Inf(1)
+		for _, perm := range permutations([]int{0, 1, 2, 3}) {
+			var cost float64
+			for i, j := range perm {
+				cost += costs[i/2][j] / 4
+			}
+			expected = math.Min(expected, cost)
+		}
+		actual := transportCost([]float64{0.5, 0.5}, []float64{0.25, 0.25, 0.25, 0.25},
+			costs)
+		if math.Abs(actual-expected) > 1e-8 {
+			t.Fatalf("expected %f but got %f", expected, actual)
+		}
+	}
+}
+
+func TestWMDNearest(t *testing.T) {
+	wmd, tokens := randomWMD()
+	var docs [][]string
+	for i := 0; i < 30; i++ {
+		docs = append(docs, randomDocument(tokens))
+	}
+	docs = append(docs, []string{"unknown"})
+	query := randomDocument(tokens)
+	indices, dists := wmd.Nearest(query, docs, 5)
+	if len(indices) != 5 || len(dists) != 5 {
+		t.Fatalf("expected 5 results but got %d", len(indices))
+	}
+	all := make([]float64, len(docs))
+	for i, doc := range docs {
+		all[i] = wmd.Distance(query, doc)
+	}
+	for i, index := range indices {
+		if math.Abs(dists[i]-all[index]) > 1e-8 {
+			t.Errorf("result %d: expected distance %f but got %f", i, all[index],
+				dists[i])
+		}
+	}
+	sort.Float64s(all)
+	for i, d := range dists {
+		if math.Abs(d-all[i]) > 1e-8 {
+			t.Errorf("result %d: expected distance %f but got %f", i, all[i], d)
+		}
+	}
+}
+
+func TestRelaxedWMDBound(t *testing.T) {
+	wmd, tokens := randomWMD()
+	for trial := 0; trial < 20; trial++ {
+		doc1 := randomDocument(tokens)
+		doc2 := randomDocument(tokens)
+		d := wmd.Distance(doc1, doc2)
+		rd := wmd.RelaxedDistance(doc1, doc2)
+		if rd > d+1e-5 {
+			t.Errorf("relaxed distance %f exceeds distance %f", rd, d)
+		}
+		if d1 := wmd.Distance(doc2, doc1); math.Abs(d-d1) > 1e-5 {
+			t.Errorf("asymmetric distance: %f vs %f", d, d1)
+		}
+	}
+}
+
+func BenchmarkTransportCost(b *testing.B) {
+	const size = 150
+	weights := make([]float64, size)
+	costs := make([][]float64, size)
+	for i := range costs {
+		weights[i] = 1.0 / size
+		costs[i] = make([]float64, size)
+		for j := range costs[i] {
+			costs[i][j] = rand.Float64()
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		transportCost(weights, weights, costs)
+	}
+}
+
+func randomWMD() (*WMD, wordembed.TokenSet) {
+	var tokens wordembed.TokenSet
+	for _, c := range "abcdefghij" {
+		tokens = append(tokens, string(c))
+	}
+	vecs := anyvec32.MakeVector(tokens.NumIDs() * 5)
+	anyvec.Rand(vecs, anyvec.Normal, nil)
+	return NewWMD(&glove.Embedding{
+		Tokens:  tokens,
+		Vectors: &anyvec.Matrix{Data: vecs, Rows: tokens.NumIDs(), Cols: 5},
+	}), tokens
+}
+
+func randomDocument(tokens wordembed.TokenSet) []string {
+	doc := make([]string, rand.Intn(8)+1)
+	for i := range doc {
+		doc[i] = tokens[rand.Intn(len(tokens))]
+	}
+	return doc
+}
+
+func permutations(values []int) [][]int {
+	if len(values) <= 1 {
+		return [][]int{append([]int{}, values...)}
+	}
+	var res [][]int
+	for i, first := range values {
+		rest := append(append([]int{}, values[:i]...), values[i+1:]...)
+		for _, perm := range permutations(rest) {
+			res = append(res, append([]int{first}, perm...))
+		}
+	}
+	return res
+}
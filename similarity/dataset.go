@@ -0,0 +1,107 @@
+// Package similarity evaluates word embeddings on word
+// similarity benchmarks such as WordSim-353 and SimLex-999.
+package similarity
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/unixpickle/essentials"
+)
+
+// A Pair is a pair of words with a human similarity
+// score.
+type Pair struct {
+	Word1 string
+	Word2 string
+	Score float64
+}
+
+// A Format describes the layout of a word similarity
+// dataset.
+//
+// Every line of a dataset contains fields separated by
+// tabs, spaces, or commas.
+// The first two fields are the words.
+type Format struct {
+	// ScoreColumn is the index of the field containing the
+	// human similarity score.
+	ScoreColumn int
+
+	// Header indicates that the first line should be
+	// skipped.
+	Header bool
+
+	// Lowercase indicates that words should be converted to
+	// lower case.
+	Lowercase bool
+}
+
+// Formats of common datasets.
+var (
+	// WordSim353 is the format of the WordSim-353
+	// combined.csv and combined.tab files.
+	WordSim353 = Format{ScoreColumn: 2, Header: true}
+
+	// SimLex999 is the format of the SimLex-999.txt file.
+	SimLex999 = Format{ScoreColumn: 3, Header: true}
+
+	// MEN is the format of the MEN_dataset_natural_form_full
+	// file.
+	MEN = Format{ScoreColumn: 2}
+
+	// RW is the format of the Stanford Rare Word rw.txt
+	// file.
+	RW = Format{ScoreColumn: 2}
+)
+
+// LoadPairs reads a dataset from a file.
+func LoadPairs(path string, f Format) ([]*Pair, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, essentials.AddCtx("load pairs", err)
+	}
+	defer file.Close()
+	return ReadPairs(file, f)
+}
+
+// ReadPairs reads a dataset.
+//
+// Empty lines and lines starting with "#" are skipped.
+func ReadPairs(r io.Reader, f Format) (pairs []*Pair, err error) {
+	defer essentials.AddCtxTo("read pairs", &err)
+	scanner := bufio.NewScanner(r)
+	for lineIdx := 1; scanner.Scan(); lineIdx++ {
+		if lineIdx == 1 && f.Header {
+			continue
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ' ' || r == '\t' || r == ','
+		})
+		if len(fields) <= f.ScoreColumn || f.ScoreColumn < 2 {
+			return nil, fmt.Errorf("line %d: missing score", lineIdx)
+		}
+		score, err := strconv.ParseFloat(fields[f.ScoreColumn], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineIdx, err.Error())
+		}
+		pair := &Pair{Word1: fields[0], Word2: fields[1], Score: score}
+		if f.Lowercase {
+			pair.Word1 = strings.ToLower(pair.Word1)
+			pair.Word2 = strings.ToLower(pair.Word2)
+		}
+		pairs = append(pairs, pair)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return pairs, nil
+}
@@ -0,0 +1,41 @@
+package similarity
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadPairs(t *testing.T) {
+	data := "word1\tword2\tPOS\tSimLex999\tconc(w1)\n" +
+		"Old\tnew\tA\t1.58\t2.72\n" +
+		"\n" +
+		"smart\tintelligent\tA\t9.2\t1.75\n"
+	format := SimLex999
+	format.Lowercase = true
+	pairs, err := ReadPairs(strings.NewReader(data), format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []*Pair{
+		{Word1: "old", Word2: "new", Score: 1.58},
+		{Word1: "smart", Word2: "intelligent", Score: 9.2},
+	}
+	if !reflect.DeepEqual(pairs, expected) {
+		t.Errorf("expected %v but got %v", expected, pairs)
+	}
+
+	pairs, err = ReadPairs(strings.NewReader("Word 1,Word 2,Human (mean)\nlove,sex,6.77\n"),
+		WordSim353)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []*Pair{{Word1: "love", Word2: "sex", Score: 6.77}}
+	if !reflect.DeepEqual(pairs, expected) {
+		t.Errorf("expected %v but got %v", expected, pairs)
+	}
+
+	if _, err := ReadPairs(strings.NewReader("a b\n"), MEN); err == nil {
+		t.Error("expected error for missing score")
+	}
+}
@@ -0,0 +1,177 @@
+package similarity
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/wordembed"
+)
+
+// OOVMode determines how pairs with out-of-vocabulary
+// words are handled.
+type OOVMode int
+
+const (
+	// SkipOOV ignores pairs with out-of-vocabulary words.
+	SkipOOV OOVMode = iota
+
+	// ZeroOOV uses a zero vector for out-of-vocabulary
+	// words, giving a similarity of zero.
+	ZeroOOV
+
+	// FallbackOOV uses whatever vector the embedding
+	// returns from Embed for out-of-vocabulary words.
+	// For example, this is the unknown token's vector for
+	// a glove.Embedding, or a vector built from character
+	// n-grams for a word2vec.SubwordEmbedding.
+	FallbackOOV
+)
+
+// A Result summarizes the performance of an embedding on
+// a similarity dataset.
+type Result struct {
+	// Total is the number of pairs in the dataset.
+	Total int
+
+	// Found is the number of pairs where both words were
+	// in the embedding's vocabulary.
+	Found int
+
+	// Evaluated is the number of pairs used to compute the
+	// correlations.
+	Evaluated int
+
+	// Spearman is the Spearman rank correlation between
+	// the human scores and the cosine similarities.
+	Spearman float64
+
+	// Pearson is the Pearson correlation between the human
+	// scores and the cosine similarities.
+	Pearson float64
+}
+
+// Coverage returns the fraction of pairs where both words
+// were in the vocabulary.
+func (r *Result) Coverage() float64 {
+	if r.Total == 0 {
+		return 0
+	}
+	return float64(r.Found) / float64(r.Total)
+}
+
+func (r *Result) String() string {
+	return fmt.Sprintf("spearman=%.4f pearson=%.4f coverage=%.4f (%d/%d)", r.Spearman,
+		r.Pearson, r.Coverage(), r.Found, r.Total)
+}
+
+// Evaluate computes the correlation between the human
+// scores and the cosine similarities from an embedding.
+//
+// A word is out-of-vocabulary if it is not among the
+// embedding's tokens, as listed by EmbeddingTokens.
+func Evaluate(e wordembed.Embedding, pairs []*Pair, mode OOVMode) *Result {
+	vocab := map[string]bool{}
+	for _, token := range wordembed.EmbeddingTokens(e) {
+		vocab[token] = true
+	}
+
+	res := &Result{Total: len(pairs)}
+	var human, model []float64
+	for _, pair := range pairs {
+		found := vocab[pair.Word1] && vocab[pair.Word2]
+		if found {
+			res.Found++
+		}
+		var sim float64
+		switch {
+		case found || mode == FallbackOOV:
+			sim = cosineSimilarity(e.Embed(pair.Word1), e.Embed(pair.Word2))
+		case mode == SkipOOV:
+			continue
+		case mode == ZeroOOV:
+			sim = 0
+		default:
+			panic("unknown OOV mode")
+		}
+		human = append(human, pair.Score)
+		model = append(model, sim)
+	}
+	res.Evaluated = len(human)
+	res.Pearson = Pearson(human, model)
+	res.Spearman = Spearman(human, model)
+	return res
+}
+
+// Pearson computes the Pearson correlation coefficient.
+//
+// If either list is constant, the result is 0.
+func Pearson(x, y []float64) float64 {
+	if len(x) != len(y) {
+		panic("length mismatch")
+	}
+	if len(x) == 0 {
+		return 0
+	}
+	meanX, meanY := mean(x), mean(y)
+	var cov, varX, varY float64
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varX*varY)
+}
+
+// Spearman computes the Spearman rank correlation
+// coefficient.
+//
+// Tied values are assigned the average of their ranks.
+func Spearman(x, y []float64) float64 {
+	return Pearson(ranks(x), ranks(y))
+}
+
+func ranks(x []float64) []float64 {
+	indices := make([]int, len(x))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.Slice(indices, func(i, j int) bool {
+		return x[indices[i]] < x[indices[j]]
+	})
+	res := make([]float64, len(x))
+	for start := 0; start < len(indices); {
+		end := start + 1
+		for end < len(indices) && x[indices[end]] == x[indices[start]] {
+			end++
+		}
+		rank := float64(start+end-1)/2 + 1
+		for _, idx := range indices[start:end] {
+			res[idx] = rank
+		}
+		start = end
+	}
+	return res
+}
+
+func mean(x []float64) float64 {
+	var sum float64
+	for _, v := range x {
+		sum += v
+	}
+	return sum / float64(len(x))
+}
+
+func cosineSimilarity(v1, v2 anyvec.Vector) float64 {
+	norms := wordembed.NumericFloat64(anyvec.Norm(v1)) *
+		wordembed.NumericFloat64(anyvec.Norm(v2))
+	if norms == 0 {
+		return 0
+	}
+	return wordembed.NumericFloat64(v1.Dot(v2)) / norms
+}
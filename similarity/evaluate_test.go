@@ -0,0 +1,74 @@
+package similarity
+
+import (
+	"math"
+	"testing"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvec32"
+	"github.com/unixpickle/wordembed"
+	"github.com/unixpickle/wordembed/glove"
+)
+
+func TestSpearman(t *testing.T) {
+	x := []float64{1, 2, 3, 4, 5}
+	y := []float64{5, 6, 7, 8, 7}
+	if actual := Spearman(x, y); math.Abs(actual-0.8207826816681233) > 1e-8 {
+		t.Errorf("unexpected Spearman correlation: %f", actual)
+	}
+	if actual := Pearson(x, []float64{2, 4, 6, 8, 10}); math.Abs(actual-1) > 1e-8 {
+		t.Errorf("unexpected Pearson correlation: %f", actual)
+	}
+	if actual := Spearman(x, []float64{1, 1, 1, 1, 1}); actual != 0 {
+		t.Errorf("expected 0 for constant input but got %f", actual)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	tokens := wordembed.TokenSet{"a", "b", "c", "d"}
+	e := &glove.Embedding{
+		Tokens: tokens,
+		Vectors: &anyvec.Matrix{
+			Data: anyvec32.MakeVectorData([]float32{
+				1, 0,
+				1, 0.1,
+				0, 1,
+				-1, 0.5,
+				1, 1,
+			}),
+			Rows: tokens.NumIDs(),
+			Cols: 2,
+		},
+	}
+	pairs := []*Pair{
+		{Word1: "a", Word2: "b", Score: 9},
+		{Word1: "a", Word2: "c", Score: 5},
+		{Word1: "a", Word2: "d", Score: 1},
+		{Word1: "a", Word2: "x", Score: 10},
+	}
+
+	res := Evaluate(e, pairs, SkipOOV)
+	if res.Total != 4 || res.Found != 3 || res.Evaluated != 3 {
+		t.Errorf("unexpected counts: %+v", res)
+	}
+	if math.Abs(res.Spearman-1) > 1e-8 {
+		t.Errorf("unexpected Spearman correlation: %f", res.Spearman)
+	}
+	if res.Coverage() != 0.75 {
+		t.Errorf("unexpected coverage: %f", res.Coverage())
+	}
+
+	// The zero vector gives the OOV pair the same rank as
+	// the orthogonal pair.
+	res = Evaluate(e, pairs, ZeroOOV)
+	if res.Evaluated != 4 || math.Abs(res.Spearman-0.6324555320336759) > 1e-8 {
+		t.Errorf("unexpected zero-OOV result: %+v", res)
+	}
+
+	// The unknown vector (1, 1) gives the OOV pair a high
+	// similarity.
+	res = Evaluate(e, pairs, FallbackOOV)
+	if res.Evaluated != 4 || math.Abs(res.Spearman-0.8) > 1e-8 {
+		t.Errorf("unexpected fallback result: %+v", res)
+	}
+}
@@ -0,0 +1,180 @@
+package word2vec
+
+import (
+	"math/rand"
+
+	"github.com/unixpickle/anyvec"
+)
+
+// CBOW can train continuous bag-of-words models.
+//
+// Unlike a skip-gram model, a CBOW model averages the
+// encoder rows of the context words and uses the result
+// to predict the center word.
+type CBOW struct {
+	Net       *Net
+	Hierarchy Hierarchy
+	Samples   []*Sample
+
+	// NegSampler, if non-nil, is used to train the model
+	// with negative sampling instead of hierarchical
+	// softmax.
+	// In this case, Hierarchy is not used and may be nil.
+	NegSampler *NegSampler
+
+	// Subsampler, if non-nil, is used to randomly discard
+	// frequent words from both the center word and the
+	// context of each sample.
+	// Its threshold is recorded in the Net.
+	Subsampler *Subsampler
+
+	// StepSize should be negative for gradient descent.
+	StepSize anyvec.Numeric
+
+	// Schedule, if non-nil, is used to adjust the step size
+	// as training progresses.
+	Schedule Schedule
+
+	// TotalWords is the total number of center words which
+	// will be processed during training.
+	// It is used to measure the progress of training.
+	//
+	// If this is 0 and a Schedule is used, TrainCorpus
+	// counts the words in the corpus before training, and
+	// Train panics.
+	// If this is 0 and no Schedule is used, TrainCorpus
+	// measures progress in completed epochs, and Train
+	// cannot measure progress.
+	TotalWords int64
+
+	// The minimum and maximum number of neighbors to use as
+	// context during training.
+	//
+	// If these are 0, the defaults from the word2vec paper
+	// are used.
+	MinDist int
+	MaxDist int
+
+	// NumWorkers is the number of goroutines to use for
+	// training.
+	// Workers update the parameters concurrently without
+	// locking, in the style of Hogwild!.
+	//
+	// If this is 0, runtime.GOMAXPROCS(0) is used.
+	NumWorkers int
+
+	// StatusFunc, if non-nil, is called after every training
+	// iteration with the cost from that iteration.
+	//
+	// Calls to StatusFunc are never concurrent, even when
+	// multiple workers are used.
+	StatusFunc func(lastCost anyvec.Numeric)
+
+	// ProgressFunc, if non-nil, is called after every
+	// training iteration with detailed progress info.
+	//
+	// Calls to ProgressFunc are never concurrent.
+	ProgressFunc func(p *Progress)
+}
+
+// Train trains the CBOW model until the done channel is
+// closed.
+//
+// Training is performed on NumWorkers goroutines which
+// update the parameters without any locking.
+func (c *CBOW) Train(done <-chan struct{}) {
+	state := newSampleState(vocabIndices(c.Hierarchy, c.NegSampler), c.TotalWords,
+		c.Schedule)
+	c.Subsampler.record(c.Net)
+	runWorkers(c.NumWorkers, done, func() {
+		sample := c.Samples[rand.Intn(len(c.Samples))]
+		c.trainRaw(state, sample)
+	})
+}
+
+// TrainCorpus trains the CBOW model by streaming the
+// sentences of a corpus, making the given number of passes
+// over the corpus.
+//
+// Samples are generated on the fly from each sentence,
+// and the Samples field is not used.
+//
+// Training is performed on NumWorkers goroutines which
+// update the parameters without any locking.
+func (c *CBOW) TrainCorpus(corpus Corpus, epochs int) error {
+	state, err := newCorpusState(vocabIndices(c.Hierarchy, c.NegSampler), corpus, epochs,
+		c.TotalWords, c.Schedule)
+	if err != nil {
+		return err
+	}
+	c.Subsampler.record(c.Net)
+	return streamCorpus(corpus, epochs, c.NumWorkers, func(epoch int, sentence []string) {
+		state.SetEpoch(epoch)
+		for _, sample := range AllSamples(sentence) {
+			c.trainRaw(state, sample)
+		}
+	})
+}
+
+// trainRaw trims and subsamples a sample, trains on the
+// result, and reports the cost to the callbacks.
+func (c *CBOW) trainRaw(state *trainState, sample *Sample) {
+	creator := c.Net.Encoder.Vector.Creator()
+	stepSize := state.StepSize(creator, c.StepSize, c.Schedule)
+	state.AddWord()
+
+	sample = sample.Trim(randomRadius(c.MinDist, c.MaxDist))
+	if c.Subsampler != nil {
+		if sample = c.Subsampler.Apply(sample); sample == nil {
+			return
+		}
+	}
+	if cost, ok := c.trainSample(state.W2I, sample, stepSize); ok {
+		state.Report(cost, stepSize, c.StatusFunc, c.ProgressFunc)
+	}
+}
+
+// trainSample performs a training step on a sample which
+// has already been trimmed and subsampled.
+//
+// If the center word or the entire context is outside of
+// the vocabulary, false is returned and no step is taken.
+func (c *CBOW) trainSample(w2i map[string]int, sample *Sample,
+	stepSize anyvec.Numeric) (anyvec.Numeric, bool) {
+	wordIdx, ok := w2i[sample.Word]
+	if !ok {
+		return nil, false
+	}
+	in := contextInput(c.Net.Encoder.Vector.Creator(), w2i, sample)
+	if len(in) == 0 {
+		return nil, false
+	}
+
+	if c.NegSampler != nil {
+		return c.NegSampler.Step(c.Net, in, []int{wordIdx}, stepSize), true
+	}
+	paths := c.Hierarchy.Paths([]string{sample.Word})
+	return c.Net.Step(in, paths, stepSize), true
+}
+
+// contextInput creates a sparse input which averages the
+// encoder rows for the sample's context words.
+//
+// Words outside of the vocabulary are ignored.
+func contextInput(c anyvec.Creator, w2i map[string]int, s *Sample) map[int]anyvec.Numeric {
+	counts := map[int]int{}
+	var total int
+	for _, words := range [][]string{s.Left, s.Right} {
+		for _, word := range words {
+			if idx, ok := w2i[word]; ok {
+				counts[idx]++
+				total++
+			}
+		}
+	}
+	res := map[int]anyvec.Numeric{}
+	for idx, count := range counts {
+		res[idx] = c.MakeNumeric(float64(count) / float64(total))
+	}
+	return res
+}
@@ -0,0 +1,122 @@
+package word2vec
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvec32"
+	"github.com/unixpickle/wordembed"
+)
+
+func TestContextInput(t *testing.T) {
+	w2i := map[string]int{"a": 0, "b": 1, "c": 2}
+	sample := &Sample{
+		Left:  []string{"a", "foo", "b"},
+		Word:  "c",
+		Right: []string{"a", "a"},
+	}
+	actual := contextInput(anyvec32.CurrentCreator(), w2i, sample)
+	expected := map[int]anyvec.Numeric{0: float32(0.75), 1: float32(0.25)}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v but got %v", expected, actual)
+	}
+}
+
+func TestCBOWTrainCorpus(t *testing.T) {
+	var corpus SliceCorpus
+	for i := 0; i < 5; i++ {
+		corpus = append(corpus, []string{"a", "b", "c", "d"}, []string{"d", "c", "b", "a"})
+	}
+	counts := wordembed.TokenCounts{}
+	for _, sentence := range corpus {
+		for _, word := range sentence {
+			counts.Add(word)
+		}
+	}
+	c := anyvec32.CurrentCreator()
+	for _, useNeg := range []bool{false, true} {
+		trainer := newTestCBOW(c, counts, useNeg)
+		var costs []float64
+		trainer.StatusFunc = func(cost anyvec.Numeric) {
+			costs = append(costs, float64(cost.(float32)))
+		}
+		const epochs = 50
+		if err := trainer.TrainCorpus(corpus, epochs); err != nil {
+			t.Fatal(err)
+		}
+		stepsPerEpoch := len(corpus) * 4
+		if len(costs) != stepsPerEpoch*epochs {
+			t.Fatalf("neg %v: expected %d steps but got %d", useNeg, stepsPerEpoch*epochs,
+				len(costs))
+		}
+		for i, cost := range costs {
+			if math.IsNaN(cost) || math.IsInf(cost, 0) {
+				t.Fatalf("neg %v: step %d has cost %f", useNeg, i, cost)
+			}
+		}
+		window := stepsPerEpoch * 5
+		first := meanCost(costs[:window])
+		last := meanCost(costs[len(costs)-window:])
+		if last >= first {
+			t.Errorf("neg %v: cost went from %f to %f", useNeg, first, last)
+		}
+	}
+}
+
+func TestCBOWTrainCorpusUnknown(t *testing.T) {
+	corpus := SliceCorpus{
+		{"x", "a", "b", "y"},
+		{"z", "b", "a"},
+	}
+	counts := wordembed.TokenCounts{"a": 2, "b": 2, "c": 1}
+	c := anyvec32.CurrentCreator()
+	for _, useNeg := range []bool{false, true} {
+		trainer := newTestCBOW(c, counts, useNeg)
+		var numSteps int
+		trainer.StatusFunc = func(cost anyvec.Numeric) {
+			numSteps++
+		}
+		// The word "c" never occurs, so its row should never
+		// be updated.
+		expected := trainer.Net.Encoder.Vector.Slice(8, 12).Data()
+		if err := trainer.TrainCorpus(corpus, 3); err != nil {
+			t.Fatal(err)
+		}
+		if numSteps != 4*3 {
+			t.Errorf("neg %v: expected %d steps but got %d", useNeg, 4*3, numSteps)
+		}
+		actual := trainer.Net.Encoder.Vector.Slice(8, 12).Data()
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("neg %v: row 2 changed from %v to %v", useNeg, expected, actual)
+		}
+	}
+}
+
+func newTestCBOW(c anyvec.Creator, counts wordembed.TokenCounts, useNeg bool) *CBOW {
+	trainer := &CBOW{
+		StepSize:   c.MakeNumeric(-0.1),
+		NumWorkers: 1,
+	}
+	if useNeg {
+		trainer.NegSampler = NewNegSampler(c, counts, 4)
+		trainer.Net = NewNet(c, len(counts), 4, 0)
+	} else {
+		words := map[string]float64{}
+		for word, count := range counts {
+			words[word] = float64(count)
+		}
+		trainer.Hierarchy = BuildHierarchy(words)
+		trainer.Net = NewNet(c, len(counts), 4, trainer.Hierarchy.NumNodes())
+	}
+	return trainer
+}
+
+func meanCost(costs []float64) float64 {
+	var sum float64
+	for _, x := range costs {
+		sum += x
+	}
+	return sum / float64(len(costs))
+}
@@ -0,0 +1,216 @@
+package word2vec
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/unixpickle/wordembed"
+)
+
+// A Corpus is a collection of tokenized sentences which
+// can be read any number of times.
+type Corpus interface {
+	// Open starts reading the corpus from the beginning.
+	Open() (SentenceReader, error)
+}
+
+// A SentenceReader reads the tokenized sentences of a
+// Corpus one at a time.
+type SentenceReader interface {
+	io.Closer
+
+	// ReadSentence reads the next sentence.
+	// At the end of the corpus, it returns io.EOF.
+	ReadSentence() ([]string, error)
+}
+
+// SliceCorpus is an in-memory Corpus.
+type SliceCorpus [][]string
+
+// Open starts reading the corpus.
+func (s SliceCorpus) Open() (SentenceReader, error) {
+	return &sliceReader{Sentences: s}, nil
+}
+
+// maxSentenceLength is the maximum number of tokens in a
+// sentence from a TextCorpus, as in the original word2vec.
+const maxSentenceLength = 1000
+
+// TextCorpus is a Corpus stored in a text file with one
+// sentence (or document) per line.
+//
+// The file is read incrementally, so it need not fit in
+// memory.
+// Lines with more than 1000 tokens are split into multiple
+// sentences, so files without newlines (such as text8)
+// can also be used.
+type TextCorpus struct {
+	Path string
+
+	// Tokenizer is used to split lines into tokens.
+	//
+	// If nil, a default Tokenizer is used.
+	Tokenizer *wordembed.Tokenizer
+}
+
+// Open opens the file and starts reading it.
+func (t *TextCorpus) Open() (SentenceReader, error) {
+	f, err := os.Open(t.Path)
+	if err != nil {
+		return nil, errors.New("open corpus: " + err.Error())
+	}
+	tokenizer := t.Tokenizer
+	if tokenizer == nil {
+		tokenizer = &wordembed.Tokenizer{}
+	}
+	scanner := bufio.NewScanner(f)
+	scanner.Split(scanWordsAndNewlines)
+	return &textReader{
+		File:      f,
+		Scanner:   scanner,
+		Tokenizer: tokenizer,
+	}, nil
+}
+
+type sliceReader struct {
+	Sentences [][]string
+	Offset    int
+}
+
+func (s *sliceReader) ReadSentence() ([]string, error) {
+	if s.Offset == len(s.Sentences) {
+		return nil, io.EOF
+	}
+	s.Offset++
+	return s.Sentences[s.Offset-1], nil
+}
+
+func (s *sliceReader) Close() error {
+	return nil
+}
+
+type textReader struct {
+	File      *os.File
+	Scanner   *bufio.Scanner
+	Tokenizer *wordembed.Tokenizer
+
+	// pending stores tokens which did not fit in the last
+	// sentence.
+	pending []string
+}
+
+func (t *textReader) ReadSentence() ([]string, error) {
+	sentence := t.pending
+	t.pending = nil
+	for len(sentence) < maxSentenceLength && t.Scanner.Scan() {
+		field := t.Scanner.Text()
+		if field == "\n" {
+			if len(sentence) > 0 {
+				return sentence, nil
+			}
+			continue
+		}
+		sentence = append(sentence, t.Tokenizer.Tokenize(field)...)
+	}
+	if len(sentence) > maxSentenceLength {
+		t.pending = append([]string{}, sentence[maxSentenceLength:]...)
+		sentence = sentence[:maxSentenceLength]
+	}
+	if len(sentence) > 0 {
+		return sentence, nil
+	}
+	if err := t.Scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+func (t *textReader) Close() error {
+	return t.File.Close()
+}
+
+// scanWordsAndNewlines is like bufio.ScanWords, except
+// that every newline is returned as its own token so that
+// line boundaries are preserved.
+func scanWordsAndNewlines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	start := 0
+	for start < len(data) {
+		r, width := utf8.DecodeRune(data[start:])
+		if r == '\n' {
+			return start + width, data[start : start+width], nil
+		} else if !unicode.IsSpace(r) {
+			break
+		}
+		start += width
+	}
+	for i := start; i < len(data); {
+		r, width := utf8.DecodeRune(data[i:])
+		if unicode.IsSpace(r) {
+			return i, data[start:i], nil
+		}
+		i += width
+	}
+	if atEOF && len(data) > start {
+		return len(data), data[start:], nil
+	}
+	return start, nil, nil
+}
+
+// streamCorpus reads the corpus the given number of times,
+// calling f for each sentence on numWorkers goroutines.
+// The epoch of each sentence is passed to f as well.
+//
+// If numWorkers is 0, runtime.GOMAXPROCS(0) is used.
+func streamCorpus(c Corpus, epochs, numWorkers int, f func(epoch int, sentence []string)) error {
+	if numWorkers == 0 {
+		numWorkers = runtime.GOMAXPROCS(0)
+	}
+	for i := 0; i < epochs; i++ {
+		if err := streamEpoch(c, i, numWorkers, f); err != nil {
+			return errors.New("stream corpus: " + err.Error())
+		}
+	}
+	return nil
+}
+
+func streamEpoch(c Corpus, epoch, numWorkers int, f func(epoch int, sentence []string)) error {
+	r, err := c.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	sentences := make(chan []string, numWorkers*4)
+	var wg sync.WaitGroup
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for sentence := range sentences {
+				f(epoch, sentence)
+			}
+		}()
+	}
+
+	var readErr error
+	for {
+		sentence, err := r.ReadSentence()
+		if err != nil {
+			if err != io.EOF {
+				readErr = err
+			}
+			break
+		}
+		sentences <- sentence
+	}
+	close(sentences)
+	wg.Wait()
+
+	return readErr
+}
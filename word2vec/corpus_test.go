@@ -0,0 +1,155 @@
+package word2vec
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvec32"
+	"github.com/unixpickle/wordembed"
+)
+
+func TestTextCorpus(t *testing.T) {
+	f, err := ioutil.TempFile("", "corpus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("Hello, world.\n\n  \nThe cat sat\nno newline")
+	f.Close()
+
+	corpus := &TextCorpus{Path: f.Name()}
+	for epoch := 0; epoch < 2; epoch++ {
+		r, err := corpus.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actual [][]string
+		for {
+			sentence, err := r.ReadSentence()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			actual = append(actual, sentence)
+		}
+		r.Close()
+		expected := [][]string{
+			{"hello", ",", "world", "."},
+			{"the", "cat", "sat"},
+			{"no", "newline"},
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("expected %v but got %v", expected, actual)
+		}
+	}
+}
+
+func TestTextCorpusLongLine(t *testing.T) {
+	f, err := ioutil.TempFile("", "corpus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	var words []string
+	for i := 0; i < 2500; i++ {
+		words = append(words, fmt.Sprintf("w%d", i))
+		fmt.Fprintf(f, " w%d", i)
+	}
+	f.WriteString("\nend")
+	f.Close()
+
+	r, err := (&TextCorpus{Path: f.Name()}).Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var lengths []int
+	var actual []string
+	for {
+		sentence, err := r.ReadSentence()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		lengths = append(lengths, len(sentence))
+		actual = append(actual, sentence...)
+	}
+	if expected := []int{1000, 1000, 500, 1}; !reflect.DeepEqual(lengths, expected) {
+		t.Errorf("expected lengths %v but got %v", expected, lengths)
+	}
+	if expected := append(words, "end"); !reflect.DeepEqual(actual, expected) {
+		t.Error("unexpected tokens")
+	}
+}
+
+func TestSkipGramTrainCorpus(t *testing.T) {
+	corpus := SliceCorpus{
+		{"a", "b", "c"},
+		{"c", "b", "a", "b"},
+	}
+	counts := wordembed.TokenCounts{}
+	for _, sentence := range corpus {
+		for _, word := range sentence {
+			counts.Add(word)
+		}
+	}
+	c := anyvec32.CurrentCreator()
+	sampler := NewNegSampler(c, counts, 4)
+	var numSteps int
+	trainer := &SkipGram{
+		Net:        NewNet(c, len(sampler.Words), 4, 0),
+		NegSampler: sampler,
+		StepSize:   c.MakeNumeric(-0.1),
+		NumWorkers: 1,
+		StatusFunc: func(cost anyvec.Numeric) {
+			numSteps++
+		},
+	}
+	if err := trainer.TrainCorpus(corpus, 3); err != nil {
+		t.Fatal(err)
+	}
+	if numSteps != 7*3 {
+		t.Errorf("expected %d steps but got %d", 7*3, numSteps)
+	}
+}
+
+func TestSkipGramTrainCorpusUnknown(t *testing.T) {
+	corpus := SliceCorpus{
+		{"x", "b", "y", "c"},
+		{"c", "z", "b", "x"},
+		{"x", "b", "y"},
+	}
+	counts := wordembed.TokenCounts{"a": 1, "b": 2, "c": 2}
+	c := anyvec32.CurrentCreator()
+	for _, useNeg := range []bool{false, true} {
+		trainer := &SkipGram{
+			StepSize:   c.MakeNumeric(-0.1),
+			NumWorkers: 1,
+		}
+		if useNeg {
+			trainer.NegSampler = NewNegSampler(c, counts, 4)
+			anyvec.Rand(trainer.NegSampler.Decoder.Vector, anyvec.Normal, nil)
+			trainer.Net = NewNet(c, len(counts), 4, 0)
+		} else {
+			trainer.Hierarchy = BuildHierarchy(map[string]float64{"a": 1, "b": 2, "c": 2})
+			trainer.Net = NewNet(c, len(counts), 4, trainer.Hierarchy.NumNodes())
+		}
+		// The word "a" never occurs, so its row should never
+		// be updated, even though unknown words do occur.
+		expected := trainer.Net.Encoder.Vector.Slice(0, 4).Data()
+		if err := trainer.TrainCorpus(corpus, 3); err != nil {
+			t.Fatal(err)
+		}
+		actual := trainer.Net.Encoder.Vector.Slice(0, 4).Data()
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("neg %v: row 0 changed from %v to %v", useNeg, expected, actual)
+		}
+	}
+}
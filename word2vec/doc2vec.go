@@ -0,0 +1,245 @@
+package word2vec
+
+import (
+	"math/rand"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+)
+
+// DocMode determines how paragraph vectors are trained.
+type DocMode int
+
+const (
+	// PVDM is the distributed memory model, in which the
+	// document vector is averaged with the context word
+	// vectors to predict the center word.
+	// Word vectors are trained jointly with the document
+	// vectors.
+	PVDM DocMode = iota
+
+	// PVDBOW is the distributed bag of words model, in
+	// which the document vector alone is used to predict
+	// words from the document.
+	// Word vectors are not trained.
+	PVDBOW
+)
+
+// Doc2Vec can train paragraph vectors.
+//
+// The Net's encoder stores one row per word, followed by
+// one row per document.
+// Thus, the Net's input size should be the vocabulary size
+// plus len(Documents).
+type Doc2Vec struct {
+	Net       *Net
+	Hierarchy Hierarchy
+
+	// NegSampler, if non-nil, is used to train the model
+	// with negative sampling instead of hierarchical
+	// softmax.
+	// In this case, Hierarchy is not used and may be nil.
+	NegSampler *NegSampler
+
+	Mode DocMode
+
+	// Documents contains the tokenized training documents.
+	Documents [][]string
+
+	// StepSize should be negative for gradient descent.
+	StepSize anyvec.Numeric
+
+	// Schedule, if non-nil, is used to adjust the step size
+	// as training progresses.
+	Schedule Schedule
+
+	// TotalWords is the total number of center words which
+	// will be processed during training.
+	// It is used to measure the progress of training.
+	//
+	// If this is 0, Train cannot measure progress, and it
+	// panics if a Schedule is used.
+	TotalWords int64
+
+	// The minimum and maximum number of neighbors to use as
+	// context in PVDM mode.
+	//
+	// If these are 0, the defaults from the word2vec paper
+	// are used.
+	MinDist int
+	MaxDist int
+
+	// NumWorkers is the number of goroutines to use for
+	// training.
+	// Workers update the parameters concurrently without
+	// locking, in the style of Hogwild!.
+	//
+	// If this is 0, runtime.GOMAXPROCS(0) is used.
+	NumWorkers int
+
+	// StatusFunc, if non-nil, is called after every training
+	// iteration with the cost from that iteration.
+	//
+	// Calls to StatusFunc are never concurrent, even when
+	// multiple workers are used.
+	StatusFunc func(lastCost anyvec.Numeric)
+
+	// ProgressFunc, if non-nil, is called after every
+	// training iteration with detailed progress info.
+	//
+	// Calls to ProgressFunc are never concurrent.
+	ProgressFunc func(p *Progress)
+}
+
+// Train trains the paragraph vectors until the done
+// channel is closed.
+func (d *Doc2Vec) Train(done <-chan struct{}) {
+	state := newSampleState(vocabIndices(d.Hierarchy, d.NegSampler), d.TotalWords,
+		d.Schedule)
+	numWords := vocabSize(d.Hierarchy, d.NegSampler)
+	creator := d.Net.Encoder.Vector.Creator()
+	runWorkers(d.NumWorkers, done, func() {
+		docIdx := rand.Intn(len(d.Documents))
+		doc := d.Documents[docIdx]
+		if len(doc) == 0 {
+			return
+		}
+		stepSize := state.StepSize(creator, d.StepSize, d.Schedule)
+		state.AddWord()
+		sample := d.randomSample(doc)
+		wordRows := map[string]int{}
+		for _, word := range append(append([]string{}, sample.Left...), sample.Right...) {
+			if idx, ok := state.W2I[word]; ok {
+				wordRows[word] = idx
+			}
+		}
+		cost, ok := d.trainSample(d.Net, state.W2I, numWords+docIdx, wordRows, sample,
+			stepSize, stepSize)
+		if ok {
+			state.Report(cost, stepSize, d.StatusFunc, d.ProgressFunc)
+		}
+	})
+}
+
+// DocVector returns a copy of the vector for a training
+// document.
+func (d *Doc2Vec) DocVector(docIdx int) anyvec.Vector {
+	row := vocabSize(d.Hierarchy, d.NegSampler) + docIdx
+	return d.Net.Encoder.Vector.Slice(row*d.Net.Hidden, (row+1)*d.Net.Hidden).Copy()
+}
+
+// InferVector fits a vector for a new document, keeping
+// the rest of the model frozen.
+//
+// The document vector starts out small and is trained
+// for the given number of passes over the document, with
+// the step size decaying linearly from StepSize.
+func (d *Doc2Vec) InferVector(doc []string, epochs int) anyvec.Vector {
+	w2i := vocabIndices(d.Hierarchy, d.NegSampler)
+	c := d.Net.Encoder.Vector.Creator()
+	docVec := c.MakeVector(d.Net.Hidden)
+	randomizeDocVector(docVec, d.Net.Hidden)
+	if len(doc) == 0 {
+		return docVec
+	}
+
+	zero := c.MakeNumeric(0)
+	schedule := &LinearSchedule{}
+	totalSteps := epochs * len(doc)
+	for i := 0; i < totalSteps; i++ {
+		sample := d.randomSample(doc)
+
+		// Use a small Net containing only the document vector
+		// and copies of the context word vectors, so that
+		// updates to the words are discarded.
+		rows := []anyvec.Vector{docVec}
+		wordRows := map[string]int{}
+		for _, word := range append(append([]string{}, sample.Left...), sample.Right...) {
+			if _, ok := wordRows[word]; ok {
+				continue
+			}
+			if idx, ok := w2i[word]; ok {
+				wordRows[word] = len(rows)
+				rows = append(rows, d.Net.Encoder.Vector.Slice(idx*d.Net.Hidden,
+					(idx+1)*d.Net.Hidden))
+			}
+		}
+		tempNet := &Net{
+			In:      len(rows),
+			Hidden:  d.Net.Hidden,
+			Out:     d.Net.Out,
+			Encoder: anydiff.NewVar(c.Concat(rows...)),
+			Decoder: d.Net.Decoder,
+		}
+
+		scale := schedule.Scale(float64(i) / float64(totalSteps))
+		step := c.NumOps().Mul(d.StepSize, c.MakeNumeric(scale))
+		if _, ok := d.trainSample(tempNet, w2i, 0, wordRows, sample, zero, step); ok {
+			docVec.Set(tempNet.Encoder.Vector.Slice(0, d.Net.Hidden))
+		}
+	}
+	return docVec
+}
+
+func (d *Doc2Vec) randomSample(doc []string) *Sample {
+	idx := rand.Intn(len(doc))
+	sample := &Sample{Left: doc[:idx], Word: doc[idx], Right: doc[idx+1:]}
+	if d.Mode == PVDBOW {
+		return sample.Trim(0)
+	}
+	return sample.Trim(randomRadius(d.MinDist, d.MaxDist))
+}
+
+// trainSample performs a training step on a sample.
+//
+// The docRow is the document's row in the Net's encoder,
+// and wordRows maps context words to their rows in the
+// encoder.
+// The center word's output is determined by w2i.
+//
+// If the center word is not in the vocabulary, false is
+// returned and no step is taken.
+func (d *Doc2Vec) trainSample(net *Net, w2i map[string]int, docRow int,
+	wordRows map[string]int, sample *Sample, decoderStep,
+	encoderStep anyvec.Numeric) (anyvec.Numeric, bool) {
+	wordIdx, ok := w2i[sample.Word]
+	if !ok {
+		return nil, false
+	}
+
+	c := net.Encoder.Vector.Creator()
+	counts := map[int]int{docRow: 1}
+	total := 1
+	if d.Mode == PVDM {
+		for _, words := range [][]string{sample.Left, sample.Right} {
+			for _, word := range words {
+				if row, ok := wordRows[word]; ok {
+					counts[row]++
+					total++
+				}
+			}
+		}
+	}
+	in := map[int]anyvec.Numeric{}
+	for row, count := range counts {
+		in[row] = c.MakeNumeric(float64(count) / float64(total))
+	}
+
+	if d.NegSampler != nil {
+		outIndices, labels := d.NegSampler.sampleOutputs([]int{wordIdx})
+		return d.NegSampler.step(net, in, outIndices, labels, decoderStep,
+			encoderStep), true
+	}
+	paths := d.Hierarchy.Paths([]string{sample.Word})
+	return net.step(in, paths, decoderStep, encoderStep), true
+}
+
+// randomizeDocVector fills a new document vector with
+// small uniform values in [-0.5/hidden, 0.5/hidden), as in
+// the original word2vec.
+func randomizeDocVector(v anyvec.Vector, hidden int) {
+	c := v.Creator()
+	anyvec.Rand(v, anyvec.Uniform, nil)
+	v.AddScalar(c.MakeNumeric(-0.5))
+	v.Scale(c.MakeNumeric(1 / float64(hidden)))
+}
@@ -0,0 +1,130 @@
+package word2vec
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvec32"
+	"github.com/unixpickle/wordembed"
+)
+
+func TestDoc2VecInferFrozen(t *testing.T) {
+	docs := [][]string{
+		{"the", "cat", "sat", "on", "the", "mat"},
+		{"a", "dog", "ran", "far"},
+	}
+	counts := wordembed.TokenCounts{}
+	for _, doc := range docs {
+		for _, word := range doc {
+			counts.Add(word)
+		}
+	}
+	c := anyvec32.CurrentCreator()
+	for _, mode := range []DocMode{PVDM, PVDBOW} {
+		for _, useNeg := range []bool{false, true} {
+			d := &Doc2Vec{
+				Mode:       mode,
+				Documents:  docs,
+				StepSize:   c.MakeNumeric(-0.1),
+				NumWorkers: 1,
+			}
+			hierarchyWords := map[string]float64{}
+			for word, count := range counts {
+				hierarchyWords[word] = float64(count)
+			}
+			d.Hierarchy = BuildHierarchy(hierarchyWords)
+			d.Net = NewNet(c, len(counts)+len(docs), 5, d.Hierarchy.NumNodes())
+			if useNeg {
+				d.NegSampler = NewNegSampler(c, counts, 5)
+			}
+
+			var numSteps int
+			done := make(chan struct{})
+			d.StatusFunc = func(cost anyvec.Numeric) {
+				numSteps++
+				if numSteps == 500 {
+					close(done)
+				}
+			}
+			d.Train(done)
+
+			if d.DocVector(1).Len() != 5 {
+				t.Fatal("unexpected document vector size")
+			}
+
+			encoder := d.Net.Encoder.Vector.Copy()
+			decoder := d.Net.Decoder.Vector.Copy()
+			var nsDecoder interface{}
+			if useNeg {
+				nsDecoder = d.NegSampler.Decoder.Vector.Data()
+			}
+			vec := d.InferVector([]string{"the", "dog", "sat", "unknown"}, 5)
+			if vec.Len() != 5 {
+				t.Fatal("unexpected inferred vector size")
+			}
+			if !reflect.DeepEqual(encoder.Data(), d.Net.Encoder.Vector.Data()) ||
+				!reflect.DeepEqual(decoder.Data(), d.Net.Decoder.Vector.Data()) {
+				t.Errorf("mode %d (neg %v): inference modified the Net", mode, useNeg)
+			}
+			if useNeg && !reflect.DeepEqual(nsDecoder, d.NegSampler.Decoder.Vector.Data()) {
+				t.Errorf("mode %d: inference modified the NegSampler", mode)
+			}
+		}
+	}
+}
+
+func TestDoc2VecInferClosest(t *testing.T) {
+	docs := [][]string{
+		{"red", "green", "blue", "cyan", "pink", "teal"},
+		{"one", "two", "three", "four", "five", "six"},
+		{"cat", "dog", "cow", "pig", "hen", "owl"},
+	}
+	counts := wordembed.TokenCounts{}
+	for _, doc := range docs {
+		for _, word := range doc {
+			counts.Add(word)
+		}
+	}
+	c := anyvec32.CurrentCreator()
+	sampler := NewNegSampler(c, counts, 8)
+	var numSteps int
+	done := make(chan struct{})
+	d := &Doc2Vec{
+		Net:        NewNet(c, len(counts)+len(docs), 8, 0),
+		NegSampler: sampler,
+		Mode:       PVDBOW,
+		Documents:  docs,
+		StepSize:   c.MakeNumeric(-0.1),
+		Schedule:   &LinearSchedule{},
+		TotalWords: 5000,
+		NumWorkers: 1,
+		StatusFunc: func(cost anyvec.Numeric) {
+			numSteps++
+			if numSteps == 5000 {
+				close(done)
+			}
+		},
+	}
+	d.Train(done)
+
+	for i, doc := range docs {
+		vec := d.InferVector(doc, 50)
+		var sims []float32
+		for j := range docs {
+			sims = append(sims, cosineSimilarity(vec, d.DocVector(j)))
+		}
+		for j, sim := range sims {
+			if j != i && sim >= sims[i] {
+				t.Errorf("document %d: similarities %v", i, sims)
+				break
+			}
+		}
+	}
+}
+
+func cosineSimilarity(v1, v2 anyvec.Vector) float32 {
+	x := wordembed.NormalizeFloat32(v1.Data().([]float32))
+	y := wordembed.NormalizeFloat32(v2.Data().([]float32))
+	return wordembed.DotFloat32(x, y)
+}
@@ -0,0 +1,104 @@
+package word2vec
+
+import (
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/wordembed"
+)
+
+// Embedding wraps an Embed so that it implements the
+// wordembed.Embedding interface.
+//
+// Token IDs are indices into Model.Words.
+// As with wordembed.TokenSet, the ID len(Model.Words) is
+// used for tokens which are not in the vocabulary.
+// The embedding for this unknown ID is a zero vector.
+type Embedding struct {
+	Model *Embed
+
+	// searcher is created lazily, and it is shared by
+	// copies of the Embedding.
+	searcher *wordembed.SearcherCache
+}
+
+// Dim returns the dimensionality of the embedding.
+func (e *Embedding) Dim() int {
+	return e.Model.Matrix.Vector.Len() / len(e.Model.Words)
+}
+
+// Embed returns the embedding for the token.
+func (e *Embedding) Embed(token string) anyvec.Vector {
+	return e.EmbedID(e.Tokens().ID(token))
+}
+
+// EmbedID returns the embedding for the token ID.
+func (e *Embedding) EmbedID(id int) anyvec.Vector {
+	dim := e.Dim()
+	if id == len(e.Model.Words) {
+		return e.Model.Matrix.Vector.Creator().MakeVector(dim)
+	}
+	return e.Model.Matrix.Vector.Slice(id*dim, (id+1)*dim).Copy()
+}
+
+// Lookup finds the n closest token IDs to the given
+// vector, using cosine similarity.
+// For each ID, it also returns the similarity.
+//
+// The unknown token ID is never returned.
+// If n is greater than the number of words, then there
+// will be fewer than n results.
+//
+// The normalized vectors are cached after the first call.
+// See ClearCache for details.
+func (e *Embedding) Lookup(vec anyvec.Vector, n int) ([]int, []anyvec.Numeric) {
+	return e.cosineSearcher().Lookup(vec, n)
+}
+
+// LookupBatch is like Lookup, but it answers a query for
+// each row of a matrix using a single matrix product.
+//
+// The memory usage is proportional to the number of
+// queries times the number of words, so very large
+// batches should be split up.
+func (e *Embedding) LookupBatch(queries *anyvec.Matrix, n int) ([][]int,
+	[][]anyvec.Numeric) {
+	return e.cosineSearcher().LookupBatch(queries, n)
+}
+
+// ClearCache discards the normalized vectors cached by
+// Lookup and LookupBatch.
+//
+// The cache is cleared automatically when the model's
+// matrix is replaced.
+// However, ClearCache must be called if the model is
+// trained further, since training modifies the matrix in
+// place.
+func (e *Embedding) ClearCache() {
+	e.searcherCache().Clear()
+}
+
+// Token returns the token for the token ID.
+//
+// If the ID corresponds to the unknown token, then "" is
+// returned.
+func (e *Embedding) Token(id int) string {
+	return e.Tokens().Token(id)
+}
+
+// Tokens returns the vocabulary as a TokenSet.
+//
+// The result shares memory with Model.Words.
+func (e *Embedding) Tokens() wordembed.TokenSet {
+	return wordembed.TokenSet(e.Model.Words)
+}
+
+func (e *Embedding) cosineSearcher() *wordembed.CosineSearcher {
+	return e.searcherCache().Searcher(&anyvec.Matrix{
+		Data: e.Model.Matrix.Vector,
+		Rows: len(e.Model.Words),
+		Cols: e.Dim(),
+	})
+}
+
+func (e *Embedding) searcherCache() *wordembed.SearcherCache {
+	return wordembed.LoadSearcherCache(&e.searcher)
+}
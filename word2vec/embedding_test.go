@@ -0,0 +1,94 @@
+package word2vec
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvec32"
+	"github.com/unixpickle/wordembed"
+)
+
+func TestEmbeddingInterface(t *testing.T) {
+	vec := anyvec32.MakeVectorData([]float32{
+		1, 1,
+		0, 1,
+		1, 0,
+		0, -1,
+	})
+	var e wordembed.Embedding = &Embedding{
+		Model: &Embed{
+			Matrix: anydiff.NewVar(vec),
+			Words:  []string{"a", "b", "c", "d"},
+		},
+	}
+
+	if e.Dim() != 2 {
+		t.Errorf("expected dim 2 but got %d", e.Dim())
+	}
+
+	actual := e.Embed("c").Data().([]float32)
+	if !reflect.DeepEqual(actual, []float32{1, 0}) {
+		t.Errorf("unexpected embedding for c: %v", actual)
+	}
+	actual = e.Embed("foo").Data().([]float32)
+	if !reflect.DeepEqual(actual, []float32{0, 0}) {
+		t.Errorf("unexpected embedding for unknown token: %v", actual)
+	}
+	if tok := e.Token(4); tok != "" {
+		t.Errorf("expected empty unknown token but got %#v", tok)
+	}
+
+	ids, sims := e.Lookup(anyvec32.MakeVectorData([]float32{0.1, 2}), 10)
+	if !reflect.DeepEqual(ids, []int{1, 0, 2, 3}) {
+		t.Errorf("unexpected lookup IDs: %v", ids)
+	}
+	if sim := sims[1].(float32); sim < 0.741 || sim > 0.742 {
+		t.Errorf("unexpected similarity: %f", sim)
+	}
+}
+
+func TestEmbeddingLookupBatch(t *testing.T) {
+	e := &Embedding{
+		Model: &Embed{
+			Matrix: anydiff.NewVar(anyvec32.MakeVectorData([]float32{
+				1, 1,
+				0, 1,
+				1, 0,
+				0, -1,
+			})),
+			Words: []string{"a", "b", "c", "d"},
+		},
+	}
+	queries := &anyvec.Matrix{
+		Data: anyvec32.MakeVectorData([]float32{0.1, 2, 3, -0.5}),
+		Rows: 2,
+		Cols: 2,
+	}
+	ids, sims := e.LookupBatch(queries, 2)
+	for i := 0; i < 2; i++ {
+		query := queries.Data.Slice(i*2, (i+1)*2)
+		expectedIDs, expectedSims := e.Lookup(query, 2)
+		if !reflect.DeepEqual(ids[i], expectedIDs) {
+			t.Errorf("query %d: expected %v but got %v", i, expectedIDs, ids[i])
+		}
+		for j, sim := range sims[i] {
+			if math.Abs(float64(sim.(float32)-expectedSims[j].(float32))) > 1e-5 {
+				t.Errorf("query %d: expected %v but got %v", i, expectedSims, sims[i])
+			}
+		}
+	}
+
+	// Replacing the matrix should invalidate the cache.
+	e.Model.Matrix = anydiff.NewVar(anyvec32.MakeVectorData([]float32{
+		0, 1,
+		1, 1,
+		1, 0,
+		0, -1,
+	}))
+	if ids, _ := e.Lookup(anyvec32.MakeVectorData([]float32{0, 1}), 1); ids[0] != 0 {
+		t.Errorf("unexpected lookup after change: %v", ids)
+	}
+}
@@ -0,0 +1,233 @@
+package word2vec
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/essentials"
+	"github.com/unixpickle/wordembed"
+)
+
+// ReadBinary reads an embedding in the binary format used
+// by the original word2vec tool.
+//
+// The file starts with a line containing the number of
+// words and the dimensionality.
+// Each word is followed by a space and then the vector as
+// little-endian float32 values.
+//
+// If a word appears more than once, only its first vector
+// is used.
+func ReadBinary(c anyvec.Creator, r io.Reader) (*Embedding, error) {
+	res, err := readBinary(c, bufio.NewReader(r))
+	if err != nil {
+		return nil, errors.New("read binary embedding: " + err.Error())
+	}
+	return res, nil
+}
+
+// ReadText reads an embedding in the text format used by
+// the original word2vec tool and by fastText's .vec files.
+//
+// The file starts with a line containing the number of
+// words and the dimensionality.
+// Each subsequent line contains a word followed by the
+// components of its vector, separated by whitespace.
+//
+// If a word appears more than once, only its first vector
+// is used.
+func ReadText(c anyvec.Creator, r io.Reader) (*Embedding, error) {
+	res, err := readText(c, bufio.NewReader(r))
+	if err != nil {
+		return nil, errors.New("read text embedding: " + err.Error())
+	}
+	return res, nil
+}
+
+// WriteBinary writes an embedding in the binary format
+// used by the original word2vec tool.
+//
+// Every token ID is written, starting at 0 and stopping
+// at the first ID with an empty token.
+// This matches the semantics of wordembed.TokenSet, where
+// the unknown token is "".
+func WriteBinary(w io.Writer, e wordembed.Embedding) error {
+	bufWriter := bufio.NewWriter(w)
+	tokens := wordembed.EmbeddingTokens(e)
+	if _, err := fmt.Fprintf(bufWriter, "%d %d\n", len(tokens), e.Dim()); err != nil {
+		return errors.New("write binary embedding: " + err.Error())
+	}
+	for id, token := range tokens {
+		if _, err := bufWriter.WriteString(token + " "); err != nil {
+			return errors.New("write binary embedding: " + err.Error())
+		}
+		vec := wordembed.NumericListFloat32(e.EmbedID(id).Data())
+		if err := binary.Write(bufWriter, binary.LittleEndian, vec); err != nil {
+			return errors.New("write binary embedding: " + err.Error())
+		}
+		if err := bufWriter.WriteByte('\n'); err != nil {
+			return errors.New("write binary embedding: " + err.Error())
+		}
+	}
+	if err := bufWriter.Flush(); err != nil {
+		return errors.New("write binary embedding: " + err.Error())
+	}
+	return nil
+}
+
+// WriteText writes an embedding in the text format used
+// by the original word2vec tool.
+//
+// Token IDs are written in the same way as in
+// WriteBinary.
+func WriteText(w io.Writer, e wordembed.Embedding) error {
+	bufWriter := bufio.NewWriter(w)
+	tokens := wordembed.EmbeddingTokens(e)
+	if _, err := fmt.Fprintf(bufWriter, "%d %d\n", len(tokens), e.Dim()); err != nil {
+		return errors.New("write text embedding: " + err.Error())
+	}
+	for id, token := range tokens {
+		fields := []string{token}
+		for _, x := range wordembed.NumericListFloat32(e.EmbedID(id).Data()) {
+			fields = append(fields, strconv.FormatFloat(float64(x), 'g', -1, 32))
+		}
+		if _, err := bufWriter.WriteString(strings.Join(fields, " ") + "\n"); err != nil {
+			return errors.New("write text embedding: " + err.Error())
+		}
+	}
+	if err := bufWriter.Flush(); err != nil {
+		return errors.New("write text embedding: " + err.Error())
+	}
+	return nil
+}
+
+// readChunkSize bounds the number of values read at once,
+// so that a corrupt header cannot cause a huge allocation
+// before any data is read.
+const readChunkSize = 1 << 16
+
+func readBinary(c anyvec.Creator, r *bufio.Reader) (*Embedding, error) {
+	count, dim, err := readHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	var words []string
+	var data []float32
+	chunk := make([]float32, essentials.MinInt(dim, readChunkSize))
+	for i := 0; i < count; i++ {
+		word, err := r.ReadString(' ')
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		words = append(words, strings.TrimLeft(strings.TrimSuffix(word, " "), "\n"))
+		for remaining := dim; remaining > 0; remaining -= len(chunk) {
+			chunk = chunk[:essentials.MinInt(remaining, cap(chunk))]
+			if err := binary.Read(r, binary.LittleEndian, chunk); err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			data = append(data, chunk...)
+		}
+	}
+	return newSortedEmbedding(c, words, data, dim), nil
+}
+
+func readText(c anyvec.Creator, r *bufio.Reader) (*Embedding, error) {
+	count, dim, err := readHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	var words []string
+	var data []float32
+	for i := 0; i < count; i++ {
+		line, err := r.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return nil, unexpectedEOF(err)
+		}
+		fields := strings.Fields(line)
+		if len(fields) < dim+1 {
+			return nil, fmt.Errorf("line %d: expected %d values", i+2, dim)
+		}
+		words = append(words, strings.Join(fields[:len(fields)-dim], " "))
+		for _, field := range fields[len(fields)-dim:] {
+			x, err := strconv.ParseFloat(field, 32)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", i+2, err.Error())
+			}
+			data = append(data, float32(x))
+		}
+	}
+	return newSortedEmbedding(c, words, data, dim), nil
+}
+
+// unexpectedEOF converts io.EOF to io.ErrUnexpectedEOF,
+// since an embedding ended before the header said it
+// would.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func readHeader(r *bufio.Reader) (count, dim int, err error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return
+	}
+	fields := strings.Fields(line)
+	if len(fields) != 2 {
+		return 0, 0, errors.New("invalid header")
+	}
+	count, err = strconv.Atoi(fields[0])
+	if err != nil {
+		return
+	}
+	dim, err = strconv.Atoi(fields[1])
+	if err != nil {
+		return
+	}
+	if count <= 0 || dim <= 0 {
+		return 0, 0, errors.New("invalid header")
+	}
+	return
+}
+
+// newSortedEmbedding creates an Embedding from unsorted
+// words and their corresponding rows.
+func newSortedEmbedding(c anyvec.Creator, words []string, data []float32,
+	dim int) *Embedding {
+	perm := make([]int, len(words))
+	for i := range perm {
+		perm[i] = i
+	}
+	sort.SliceStable(perm, func(i, j int) bool {
+		return words[perm[i]] < words[perm[j]]
+	})
+
+	var sortedWords []string
+	sortedData := make([]float32, 0, len(data))
+	for _, idx := range perm {
+		word := words[idx]
+		if len(sortedWords) > 0 && sortedWords[len(sortedWords)-1] == word {
+			continue
+		}
+		sortedWords = append(sortedWords, word)
+		sortedData = append(sortedData, data[idx*dim:(idx+1)*dim]...)
+	}
+
+	matrix := c.MakeVectorData(wordembed.MakeNumericList(c, sortedData))
+	return &Embedding{
+		Model: &Embed{
+			Matrix: anydiff.NewVar(matrix),
+			Words:  sortedWords,
+		},
+	}
+}
@@ -0,0 +1,85 @@
+package word2vec
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec/anyvec32"
+	"github.com/unixpickle/wordembed"
+)
+
+func TestReadText(t *testing.T) {
+	data := "3 2\nthe 1 2\ncat -0.5 0.25\napple 3e-1 4\n"
+	e, err := ReadText(anyvec32.CurrentCreator(), strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(e.Model.Words, []string{"apple", "cat", "the"}) {
+		t.Errorf("unexpected words: %v", e.Model.Words)
+	}
+	expected := []float32{0.3, 4, -0.5, 0.25, 1, 2}
+	if !reflect.DeepEqual(e.Model.Matrix.Vector.Data(), expected) {
+		t.Errorf("expected %v but got %v", expected, e.Model.Matrix.Vector.Data())
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	vec := anyvec32.MakeVectorData([]float32{
+		1, 1.5,
+		0, -1e-3,
+		1e7, 0.1,
+	})
+	var e wordembed.Embedding = &Embedding{
+		Model: &Embed{
+			Matrix: anydiff.NewVar(vec),
+			Words:  []string{"a", "b", "c"},
+		},
+	}
+	for _, binary := range []bool{false, true} {
+		var buf bytes.Buffer
+		var actual *Embedding
+		var err error
+		if binary {
+			err = WriteBinary(&buf, e)
+		} else {
+			err = WriteText(&buf, e)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if binary {
+			actual, err = ReadBinary(anyvec32.CurrentCreator(), &buf)
+		} else {
+			actual, err = ReadText(anyvec32.CurrentCreator(), &buf)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(actual, e) {
+			t.Errorf("binary=%v: expected %v but got %v", binary, e, actual)
+		}
+	}
+}
+
+func TestReadCorruptHeader(t *testing.T) {
+	// A huge header should not cause a huge allocation
+	// before the missing data is noticed.
+	for _, binary := range []bool{false, true} {
+		var buf bytes.Buffer
+		buf.WriteString("1000000000 1000000000\nthe ")
+		var err error
+		if binary {
+			buf.Write([]byte{0, 0, 128, 63})
+			_, err = ReadBinary(anyvec32.CurrentCreator(), &buf)
+		} else {
+			buf.WriteString("1 2 3\n")
+			_, err = ReadText(anyvec32.CurrentCreator(), &buf)
+		}
+		if err == nil {
+			t.Errorf("binary=%v: expected an error", binary)
+		}
+	}
+}
@@ -1,7 +1,10 @@
 package word2vec
 
 import (
+	"math/rand"
+	"runtime"
 	"sort"
+	"sync"
 
 	"github.com/unixpickle/anydiff"
 	"github.com/unixpickle/anynet"
@@ -50,3 +53,70 @@ func pathElementNodeIndex(pathElement int) int {
 		return -pathElement - 1
 	}
 }
+
+// vocabIndices maps each word in the vocabulary to its
+// row in the encoder.
+//
+// If ns is non-nil, its vocabulary is used.
+// Otherwise, the vocabulary comes from the hierarchy.
+func vocabIndices(h Hierarchy, ns *NegSampler) map[string]int {
+	var words []string
+	if ns != nil {
+		words = ns.Words
+	} else {
+		for x := range h {
+			words = append(words, x)
+		}
+		sort.Strings(words)
+	}
+
+	res := map[string]int{}
+	for index, word := range words {
+		res[word] = index
+	}
+	return res
+}
+
+// vocabSize returns the number of words in the
+// vocabulary used by vocabIndices.
+func vocabSize(h Hierarchy, ns *NegSampler) int {
+	if ns != nil {
+		return len(ns.Words)
+	}
+	return len(h)
+}
+
+// randomRadius picks a random context radius, using the
+// default bounds if minDist and maxDist are 0.
+func randomRadius(minDist, maxDist int) int {
+	if minDist == 0 && maxDist == 0 {
+		minDist, maxDist = defaultMinDist, defaultMaxDist
+	}
+	return rand.Intn(maxDist-minDist+1) + minDist
+}
+
+// runWorkers calls f repeatedly on numWorkers goroutines
+// until the done channel is closed.
+//
+// If numWorkers is 0, runtime.GOMAXPROCS(0) is used.
+func runWorkers(numWorkers int, done <-chan struct{}, f func()) {
+	if numWorkers == 0 {
+		numWorkers = runtime.GOMAXPROCS(0)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				f()
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,126 @@
+package word2vec
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvec32"
+	"github.com/unixpickle/wordembed"
+)
+
+func TestRunWorkers(t *testing.T) {
+	done := make(chan struct{})
+	var lock sync.Mutex
+	var calls int
+	runWorkers(4, done, func() {
+		lock.Lock()
+		defer lock.Unlock()
+		calls++
+		if calls == 1000 {
+			close(done)
+		}
+	})
+	if calls < 1000 || calls > 1003 {
+		t.Errorf("unexpected number of calls: %d", calls)
+	}
+}
+
+func TestTrainStateReport(t *testing.T) {
+	// The callbacks are not synchronized, so the race
+	// detector catches any concurrent calls.
+	var statusCalls, progressCalls int
+	status := func(anyvec.Numeric) { statusCalls++ }
+	progress := func(*Progress) { progressCalls++ }
+
+	state := &trainState{}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				state.AddWord()
+				state.Report(float32(1), float32(-0.1), status, progress)
+			}
+		}()
+	}
+	wg.Wait()
+	if statusCalls != 800 || progressCalls != 800 {
+		t.Errorf("unexpected number of calls: %d status, %d progress", statusCalls,
+			progressCalls)
+	}
+}
+
+func TestSkipGramWorkers(t *testing.T) {
+	if raceEnabled {
+		t.Skip("Hogwild! training races by design")
+	}
+	var corpus SliceCorpus
+	for i := 0; i < 20; i++ {
+		corpus = append(corpus, []string{"a", "b", "c", "d", "e"}, []string{"e", "c", "a"})
+	}
+	counts := wordembed.TokenCounts{}
+	for _, sentence := range corpus {
+		for _, word := range sentence {
+			counts.Add(word)
+		}
+	}
+	c := anyvec32.CurrentCreator()
+	sampler := NewNegSampler(c, counts, 4)
+	trainer := &SkipGram{
+		Net:        NewNet(c, len(sampler.Words), 4, 0),
+		NegSampler: sampler,
+		StepSize:   c.MakeNumeric(-0.1),
+		NumWorkers: 4,
+	}
+	var active int32
+	var concurrent bool
+	var numSteps int
+	trainer.StatusFunc = func(cost anyvec.Numeric) {
+		if atomic.AddInt32(&active, 1) != 1 {
+			concurrent = true
+		}
+		numSteps++
+		atomic.AddInt32(&active, -1)
+	}
+
+	encoder := trainer.Net.Encoder.Vector.Copy().Data()
+	decoder := sampler.Decoder.Vector.Copy().Data()
+	if err := trainer.TrainCorpus(corpus, 3); err != nil {
+		t.Fatal(err)
+	}
+	if expected := 20 * 8 * 3; numSteps != expected {
+		t.Errorf("expected %d steps but got %d", expected, numSteps)
+	}
+	if reflect.DeepEqual(encoder, trainer.Net.Encoder.Vector.Data()) {
+		t.Error("encoder did not change")
+	}
+	if reflect.DeepEqual(decoder, sampler.Decoder.Vector.Data()) {
+		t.Error("decoder did not change")
+	}
+
+	trainer.Samples = AllSamples(corpus[0])
+	numSteps = 0
+	done := make(chan struct{})
+	trainer.StatusFunc = func(cost anyvec.Numeric) {
+		if atomic.AddInt32(&active, 1) != 1 {
+			concurrent = true
+		}
+		numSteps++
+		if numSteps == 500 {
+			close(done)
+		}
+		atomic.AddInt32(&active, -1)
+	}
+	trainer.Train(done)
+	if numSteps < 500 || numSteps > 503 {
+		t.Errorf("unexpected number of steps: %d", numSteps)
+	}
+
+	if concurrent {
+		t.Error("StatusFunc was called concurrently")
+	}
+}
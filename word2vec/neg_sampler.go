@@ -0,0 +1,237 @@
+package word2vec
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvecsave"
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/wordembed"
+)
+
+func init() {
+	var n NegSampler
+	serializer.RegisterTypedDeserializer(n.SerializerType(), DeserializeNegSampler)
+}
+
+const defaultNumNegative = 5
+
+// noisePower is the exponent applied to unigram counts to
+// get the noise distribution, as in the word2vec paper.
+const noisePower = 0.75
+
+// A NegSampler implements the negative sampling objective
+// for training the encoder of a Net.
+//
+// When a NegSampler is used, the Net's decoder is unused,
+// since the NegSampler keeps its own output vectors.
+type NegSampler struct {
+	// Words is the sorted vocabulary.
+	// Each word's index corresponds to a row in the Net's
+	// encoder and a row in Decoder.
+	Words []string
+
+	// Noise is the cumulative distribution function of the
+	// noise distribution.
+	// Noise[i] is the probability of sampling a word with
+	// an index less than or equal to i.
+	Noise []float64
+
+	// Decoder is the row-major matrix of output vectors.
+	Decoder *anydiff.Var
+
+	// NumNegative is the number of noise words to sample
+	// for each context word.
+	//
+	// If this is 0, the default from the word2vec paper is
+	// used.
+	NumNegative int
+}
+
+// DeserializeNegSampler deserializes a NegSampler.
+func DeserializeNegSampler(d []byte) (*NegSampler, error) {
+	var wordList serializer.Bytes
+	var noise []float64
+	var decoder *anyvecsave.S
+	var numNeg serializer.Int
+	err := serializer.DeserializeAny(d, &wordList, &noise, &decoder, &numNeg)
+	if err != nil {
+		return nil, errors.New("deserialize NegSampler: " + err.Error())
+	}
+	var words []string
+	if err := json.Unmarshal(wordList, &words); err != nil {
+		return nil, errors.New("deserialize NegSampler: " + err.Error())
+	}
+	return &NegSampler{
+		Words:       words,
+		Noise:       noise,
+		Decoder:     anydiff.NewVar(decoder.Vector),
+		NumNegative: int(numNeg),
+	}, nil
+}
+
+// NewNegSampler creates a NegSampler for the words in a
+// set of token counts.
+//
+// The noise distribution is the unigram distribution
+// raised to the 3/4 power.
+// The output vectors are initialized to zero.
+func NewNegSampler(c anyvec.Creator, counts wordembed.TokenCounts, hidden int) *NegSampler {
+	var words []string
+	for word := range counts {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+
+	noise := make([]float64, len(words))
+	var total float64
+	for i, word := range words {
+		total += math.Pow(float64(counts[word]), noisePower)
+		noise[i] = total
+	}
+	for i := range noise {
+		noise[i] /= total
+	}
+
+	return &NegSampler{
+		Words:   words,
+		Noise:   noise,
+		Decoder: anydiff.NewVar(c.MakeVector(len(words) * hidden)),
+	}
+}
+
+// Step performs a step of gradient descent for the sparse
+// input and the indices of the desired output words.
+// For each output word, noise words are sampled to serve
+// as negative examples.
+//
+// Unlike Net.Step, the cost is summed over the targets
+// rather than averaged, so each (input, target) pair gets
+// the full step size, as in the reference word2vec.
+//
+// It returns the cost before the step was taken.
+//
+// For gradient descent, the provided step size should be
+// negative.
+func (n *NegSampler) Step(net *Net, in map[int]anyvec.Numeric, targets []int,
+	step anyvec.Numeric) anyvec.Numeric {
+	if len(in) == 0 {
+		panic("cannot have empty input")
+	}
+	if len(targets) == 0 {
+		panic("cannot have empty desired output")
+	}
+	outIndices, labels := n.sampleOutputs(targets)
+	return n.step(net, in, outIndices, labels, step, step)
+}
+
+// SerializerType returns the unique ID used to serialize
+// a NegSampler with the serializer package.
+func (n *NegSampler) SerializerType() string {
+	return "github.com/unixpickle/wordembed/word2vec.NegSampler"
+}
+
+// Serialize serializes the NegSampler.
+func (n *NegSampler) Serialize() ([]byte, error) {
+	data, _ := json.Marshal(n.Words)
+	return serializer.SerializeAny(
+		serializer.Bytes(data),
+		n.Noise,
+		&anyvecsave.S{Vector: n.Decoder.Vector},
+		serializer.Int(n.NumNegative),
+	)
+}
+
+func (n *NegSampler) sampleOutputs(targets []int) (indices []int, labels []float64) {
+	numNeg := n.NumNegative
+	if numNeg == 0 {
+		numNeg = defaultNumNegative
+	}
+	for _, target := range targets {
+		indices = append(indices, target)
+		labels = append(labels, 1)
+		for i := 0; i < numNeg; i++ {
+			noise := n.sampleNoise()
+			if noise == target {
+				continue
+			}
+			indices = append(indices, noise)
+			labels = append(labels, 0)
+		}
+	}
+	return
+}
+
+func (n *NegSampler) sampleNoise() int {
+	idx := sort.SearchFloat64s(n.Noise, rand.Float64())
+	if idx == len(n.Noise) {
+		// Guard against rounding error in the last entry.
+		idx--
+	}
+	return idx
+}
+
+// step performs a step for pre-sampled outputs.
+// The decoder and encoder may use different step sizes,
+// and a zero decoder step leaves the decoder unchanged.
+func (n *NegSampler) step(net *Net, in map[int]anyvec.Numeric, outIndices []int,
+	labels []float64, decoderStep, encoderStep anyvec.Numeric) anyvec.Numeric {
+	hidden := net.encode(in)
+	c := hidden.Creator()
+
+	var rows []anyvec.Vector
+	for _, idx := range outIndices {
+		rows = append(rows, n.Decoder.Vector.Slice(idx*net.Hidden, (idx+1)*net.Hidden))
+	}
+	outMat := &anyvec.Matrix{
+		Data: c.Concat(rows...),
+		Rows: len(rows),
+		Cols: net.Hidden,
+	}
+	hiddenMat := &anyvec.Matrix{Data: hidden, Rows: net.Hidden, Cols: 1}
+	probs := &anyvec.Matrix{Data: c.MakeVector(len(rows)), Rows: len(rows), Cols: 1}
+	probs.Product(false, false, c.MakeNumeric(1), outMat, hiddenMat, c.MakeNumeric(0))
+	anyvec.Sigmoid(probs.Data)
+
+	labelVec := c.MakeVectorData(c.MakeNumericList(labels))
+
+	// The probability of each correct label is computed
+	// as (1-label) + prob*(2*label-1).
+	correctProbs := labelVec.Copy()
+	correctProbs.Scale(c.MakeNumeric(2))
+	correctProbs.AddScalar(c.MakeNumeric(-1))
+	correctProbs.Mul(probs.Data)
+	correctProbs.Sub(labelVec)
+	correctProbs.AddScalar(c.MakeNumeric(1))
+	anyvec.Log(correctProbs)
+	cost := anyvec.Sum(correctProbs)
+	cost = c.NumOps().Mul(cost, c.MakeNumeric(-1))
+
+	// Gradient of the cost with respect to the logits.
+	outGrad := probs.Data.Copy()
+	outGrad.Sub(labelVec)
+	outGradMat := &anyvec.Matrix{Data: outGrad, Rows: len(rows), Cols: 1}
+
+	hiddenGrad := &anyvec.Matrix{Data: c.MakeVector(net.Hidden), Rows: net.Hidden, Cols: 1}
+	hiddenGrad.Product(true, false, c.MakeNumeric(1), outMat, outGradMat, c.MakeNumeric(0))
+
+	// Propagate through the output vectors.
+	updates := &anyvec.Matrix{
+		Data: c.MakeVector(len(rows) * net.Hidden),
+		Rows: len(rows),
+		Cols: net.Hidden,
+	}
+	updates.Product(false, true, decoderStep, outGradMat, hiddenMat, c.MakeNumeric(0))
+	for i, row := range rows {
+		row.Add(updates.Data.Slice(i*net.Hidden, (i+1)*net.Hidden))
+	}
+
+	net.backwardEncoder(in, hiddenGrad.Data, encoderStep)
+
+	return cost
+}
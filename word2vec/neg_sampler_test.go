@@ -0,0 +1,134 @@
+package word2vec
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anydiff/anydifftest"
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvec32"
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/wordembed"
+)
+
+func TestNegSamplerNoise(t *testing.T) {
+	counts := wordembed.TokenCounts{"a": 1, "b": 16, "c": 81}
+	sampler := NewNegSampler(anyvec32.CurrentCreator(), counts, 3)
+	if !reflect.DeepEqual(sampler.Words, []string{"a", "b", "c"}) {
+		t.Fatalf("unexpected words: %v", sampler.Words)
+	}
+	expected := []float64{1.0 / 36, 9.0 / 36, 1}
+	for i, x := range expected {
+		if math.Abs(sampler.Noise[i]-x) > 1e-8 {
+			t.Errorf("noise %d: expected %f but got %f", i, x, sampler.Noise[i])
+		}
+	}
+
+	var hist [3]int
+	for i := 0; i < 10000; i++ {
+		hist[sampler.sampleNoise()]++
+	}
+	if hist[2] < 7000 || hist[0] > 600 {
+		t.Errorf("unexpected noise histogram: %v", hist)
+	}
+}
+
+func TestNegSamplerStep(t *testing.T) {
+	res := randomNegSamplerRes()
+	checker := anydifftest.ResChecker{
+		F: func() anydiff.Res {
+			return res
+		},
+		V:     []*anydiff.Var{res.Net.Encoder, res.Sampler.Decoder},
+		Delta: 1e-2,
+		Prec:  1e-3,
+	}
+	checker.FullCheck(t)
+}
+
+func TestNegSamplerSerialize(t *testing.T) {
+	counts := wordembed.TokenCounts{"a": 1, "b": 16, "c": 81}
+	sampler := NewNegSampler(anyvec32.CurrentCreator(), counts, 3)
+	sampler.NumNegative = 7
+	anyvec.Rand(sampler.Decoder.Vector, anyvec.Normal, nil)
+	data, err := serializer.SerializeAny(sampler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sampler1 *NegSampler
+	if err := serializer.DeserializeAny(data, &sampler1); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sampler, sampler1) {
+		t.Error("invalid result")
+	}
+}
+
+type negSamplerRes struct {
+	In      map[int]anyvec.Numeric
+	Outs    []int
+	Labels  []float64
+	Net     *Net
+	Sampler *NegSampler
+}
+
+func randomNegSamplerRes() *negSamplerRes {
+	c := anyvec32.CurrentCreator()
+	counts := wordembed.TokenCounts{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5}
+	sampler := NewNegSampler(c, counts, 3)
+	anyvec.Rand(sampler.Decoder.Vector, anyvec.Normal, nil)
+
+	// Keep the logits small so that the cost is close to
+	// linear over the finite difference step.
+	sampler.Decoder.Vector.Scale(float32(0.5))
+	net := NewNet(c, 5, 3, 0)
+	net.Encoder.Vector.Scale(float32(0.5))
+
+	return &negSamplerRes{
+		In:      map[int]anyvec.Numeric{1: float32(2), 4: float32(1)},
+		Outs:    []int{0, 3, 2, 2, 4, 1, 3},
+		Labels:  []float64{1, 0, 0, 1, 0, 0, 1},
+		Net:     net,
+		Sampler: sampler,
+	}
+}
+
+func (n *negSamplerRes) Output() anyvec.Vector {
+	cost := n.Sampler.step(n.Net, n.In, n.Outs, n.Labels, float32(0), float32(0)).(float32)
+	return anyvec32.MakeVectorData([]float32{cost})
+}
+
+func (n *negSamplerRes) Vars() anydiff.VarSet {
+	res := anydiff.VarSet{}
+	res.Add(n.Net.Encoder)
+	res.Add(n.Sampler.Decoder)
+	return res
+}
+
+func (n *negSamplerRes) Propagate(u anyvec.Vector, g anydiff.Grad) {
+	netCopy := &Net{
+		In:     n.Net.In,
+		Out:    n.Net.Out,
+		Hidden: n.Net.Hidden,
+
+		Encoder: anydiff.NewVar(n.Net.Encoder.Vector.Copy()),
+		Decoder: anydiff.NewVar(n.Net.Decoder.Vector.Copy()),
+	}
+	samplerCopy := *n.Sampler
+	samplerCopy.Decoder = anydiff.NewVar(n.Sampler.Decoder.Vector.Copy())
+	samplerCopy.step(netCopy, n.In, n.Outs, n.Labels, float32(1), float32(1))
+	netCopy.Encoder.Vector.Sub(n.Net.Encoder.Vector)
+	samplerCopy.Decoder.Vector.Sub(n.Sampler.Decoder.Vector)
+	uScaler := anyvec.Sum(u)
+	netCopy.Encoder.Vector.Scale(uScaler)
+	samplerCopy.Decoder.Vector.Scale(uScaler)
+
+	if vec, ok := g[n.Net.Encoder]; ok {
+		vec.Add(netCopy.Encoder.Vector)
+	}
+	if vec, ok := g[n.Sampler.Decoder]; ok {
+		vec.Add(samplerCopy.Decoder.Vector)
+	}
+}
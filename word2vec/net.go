@@ -4,6 +4,7 @@ package word2vec
 import (
 	"errors"
 	"math"
+	"sort"
 
 	"github.com/unixpickle/anydiff"
 	"github.com/unixpickle/anyvec"
@@ -28,22 +29,34 @@ type Net struct {
 
 	// Decoder is the row-major output matrix.
 	Decoder *anydiff.Var
+
+	// SubsampleThreshold is the threshold of the Subsampler
+	// used to train the Net, or 0 if frequent words were not
+	// subsampled.
+	// It is set by the trainers.
+	SubsampleThreshold float64
 }
 
 // DeserializeNet deserializes a Net.
 func DeserializeNet(d []byte) (*Net, error) {
 	var in, hidden, out serializer.Int
 	var encoder, decoder *anyvecsave.S
-	err := serializer.DeserializeAny(d, &in, &hidden, &out, &encoder, &decoder)
+	var threshold float64
+	err := serializer.DeserializeAny(d, &in, &hidden, &out, &encoder, &decoder, &threshold)
 	if err != nil {
-		return nil, errors.New("deserialize net: " + err.Error())
+		// Nets saved without a subsampling threshold.
+		err = serializer.DeserializeAny(d, &in, &hidden, &out, &encoder, &decoder)
+		if err != nil {
+			return nil, errors.New("deserialize net: " + err.Error())
+		}
 	}
 	return &Net{
-		In:      int(in),
-		Hidden:  int(hidden),
-		Out:     int(out),
-		Encoder: anydiff.NewVar(encoder.Vector),
-		Decoder: anydiff.NewVar(decoder.Vector),
+		In:                 int(in),
+		Hidden:             int(hidden),
+		Out:                int(out),
+		Encoder:            anydiff.NewVar(encoder.Vector),
+		Decoder:            anydiff.NewVar(decoder.Vector),
+		SubsampleThreshold: threshold,
 	}, nil
 }
 
@@ -72,6 +85,32 @@ func NewNet(c anyvec.Creator, in, hidden, out int) *Net {
 // For gradient descent, the provided step size should be
 // negative.
 func (n *Net) Step(in map[int]anyvec.Numeric, paths [][]int, step anyvec.Numeric) anyvec.Numeric {
+	return n.step(in, paths, step, step)
+}
+
+// SerializerType returns the unique ID used to serialize
+// a Net with the serializer package.
+func (n *Net) SerializerType() string {
+	return "github.com/unixpickle/wordembed/word2vec.Net"
+}
+
+// Serialize serializes the Net.
+func (n *Net) Serialize() ([]byte, error) {
+	return serializer.SerializeAny(
+		serializer.Int(n.In),
+		serializer.Int(n.Hidden),
+		serializer.Int(n.Out),
+		&anyvecsave.S{Vector: n.Encoder.Vector},
+		&anyvecsave.S{Vector: n.Decoder.Vector},
+		n.SubsampleThreshold,
+	)
+}
+
+// step is like Step, but it allows the decoder and the
+// encoder to use different step sizes.
+// A zero decoder step leaves the decoder unchanged.
+func (n *Net) step(in map[int]anyvec.Numeric, paths [][]int,
+	decoderStep, encoderStep anyvec.Numeric) anyvec.Numeric {
 	if len(in) == 0 {
 		panic("cannot have empty input")
 	}
@@ -99,39 +138,15 @@ func (n *Net) Step(in map[int]anyvec.Numeric, paths [][]int, step anyvec.Numeric
 
 	outGrad := grad[actualRes]
 
-	n.backward(in, hidden, out, outGrad, sortedNodesInPaths(paths), step)
+	n.backward(in, hidden, out, outGrad, sortedNodesInPaths(paths), decoderStep,
+		encoderStep)
 
 	return anyvec.Sum(cost.Output())
 }
 
-// SerializerType returns the unique ID used to serialize
-// a Net with the serializer package.
-func (n *Net) SerializerType() string {
-	return "github.com/unixpickle/wordembed/word2vec.Net"
-}
-
-// Serialize serializes the Net.
-func (n *Net) Serialize() ([]byte, error) {
-	return serializer.SerializeAny(
-		serializer.Int(n.In),
-		serializer.Int(n.Hidden),
-		serializer.Int(n.Out),
-		&anyvecsave.S{Vector: n.Encoder.Vector},
-		&anyvecsave.S{Vector: n.Decoder.Vector},
-	)
-}
-
 func (n *Net) forward(in map[int]anyvec.Numeric, paths [][]int) (hidden, out anyvec.Vector) {
-	temp := n.Encoder.Vector.Creator().MakeVector(n.Hidden)
-	for i, v := range in {
-		temp.Set(n.Encoder.Vector.Slice(i*n.Hidden, (i+1)*n.Hidden))
-		temp.Scale(v)
-		if hidden == nil {
-			hidden = temp.Copy()
-		} else {
-			hidden.Add(temp)
-		}
-	}
+	hidden = n.encode(in)
+	temp := hidden.Creator().MakeVector(n.Hidden)
 	var outNums []anyvec.Vector
 	for _, i := range sortedNodesInPaths(paths) {
 		temp.Set(n.Decoder.Vector.Slice(i*n.Hidden, (i+1)*n.Hidden))
@@ -145,7 +160,7 @@ func (n *Net) forward(in map[int]anyvec.Numeric, paths [][]int) (hidden, out any
 }
 
 func (n *Net) backward(in map[int]anyvec.Numeric, hidden, out, outGrad anyvec.Vector,
-	outIndices []int, stepSize anyvec.Numeric) {
+	outIndices []int, decoderStep, encoderStep anyvec.Numeric) {
 	var hiddenGrad anyvec.Vector
 
 	tempGrad := out.Creator().MakeVector(n.Hidden)
@@ -167,12 +182,24 @@ func (n *Net) backward(in map[int]anyvec.Numeric, hidden, out, outGrad anyvec.Ve
 
 		tempGrad.Set(hidden)
 		tempGrad.Scale(upstreamScaler)
-		tempGrad.Scale(stepSize)
+		tempGrad.Scale(decoderStep)
 
 		oldRow.Add(tempGrad)
 	}
 
-	// Propagate through the hidden layer.
+	n.backwardEncoder(in, hiddenGrad, encoderStep)
+}
+
+// encode computes the hidden vector for a sparse input.
+func (n *Net) encode(in map[int]anyvec.Numeric) anyvec.Vector {
+	return encodeSparse(n.Encoder.Vector, n.Hidden, in)
+}
+
+// backwardEncoder propagates a hidden gradient through
+// the encoder and applies the resulting update.
+func (n *Net) backwardEncoder(in map[int]anyvec.Numeric, hiddenGrad anyvec.Vector,
+	stepSize anyvec.Numeric) {
+	tempGrad := hiddenGrad.Creator().MakeVector(n.Hidden)
 	for inIndex, scaler := range in {
 		rowStart := inIndex * n.Hidden
 		oldRow := n.Encoder.Vector.Slice(rowStart, rowStart+n.Hidden)
@@ -184,3 +211,25 @@ func (n *Net) backward(in map[int]anyvec.Numeric, hidden, out, outGrad anyvec.Ve
 		oldRow.Add(tempGrad)
 	}
 }
+
+// encodeSparse computes a weighted sum of rows in an
+// encoder matrix.
+//
+// If the input is empty, the result is a zero vector.
+func encodeSparse(encoder anyvec.Vector, hidden int, in map[int]anyvec.Numeric) anyvec.Vector {
+	// Sort the indices so that rounding is deterministic.
+	indices := make([]int, 0, len(in))
+	for i := range in {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+
+	res := encoder.Creator().MakeVector(hidden)
+	temp := encoder.Creator().MakeVector(hidden)
+	for _, i := range indices {
+		temp.Set(encoder.Slice(i*hidden, (i+1)*hidden))
+		temp.Scale(in[i])
+		res.Add(temp)
+	}
+	return res
+}
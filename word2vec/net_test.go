@@ -27,6 +27,7 @@ func TestNetStep(t *testing.T) {
 func TestNetSerialize(t *testing.T) {
 	c := anyvec32.DefaultCreator{}
 	net := NewNet(c, 15, 20, 10)
+	net.SubsampleThreshold = 1e-4
 	data, err := serializer.SerializeAny(net)
 	if err != nil {
 		t.Fatal(err)
@@ -0,0 +1,154 @@
+package word2vec
+
+import (
+	"errors"
+	"io"
+	"math"
+	"sync"
+	"sync/atomic"
+
+	"github.com/unixpickle/anyvec"
+)
+
+// Progress describes the state of training after a
+// training step.
+type Progress struct {
+	// Cost is the cost from the step.
+	Cost anyvec.Numeric
+
+	// StepSize is the step size used for the step.
+	StepSize anyvec.Numeric
+
+	// Words is the number of center words processed so far,
+	// including words discarded by subsampling.
+	Words int64
+
+	// Epoch is the current pass over the corpus, starting
+	// at 0.
+	Epoch int
+
+	// Fraction is the fraction of training completed, or 0
+	// if the length of training is unknown.
+	Fraction float64
+}
+
+// trainState tracks a single training run.
+type trainState struct {
+	W2I map[string]int
+
+	// TotalWords and NumEpochs are used to measure the
+	// fraction of training completed.
+	// If both are 0, progress is unknown.
+	TotalWords int64
+	NumEpochs  int
+
+	statusLock sync.Mutex
+	words      int64
+	epoch      int64
+}
+
+// newCorpusState creates a trainState for training on a
+// corpus.
+//
+// If a schedule is used but totalWords is 0, the corpus is
+// read once to count its words, so that the step size can
+// decay smoothly even within a single epoch.
+func newCorpusState(w2i map[string]int, c Corpus, epochs int, totalWords int64,
+	s Schedule) (*trainState, error) {
+	if totalWords == 0 && s != nil {
+		count, err := countWords(c)
+		if err != nil {
+			return nil, errors.New("count words: " + err.Error())
+		}
+		totalWords = count * int64(epochs)
+	}
+	return &trainState{W2I: w2i, TotalWords: totalWords, NumEpochs: epochs}, nil
+}
+
+// newSampleState creates a trainState for training on
+// random samples.
+//
+// It panics if a schedule is used without totalWords,
+// since the length of training would be unknown.
+func newSampleState(w2i map[string]int, totalWords int64, s Schedule) *trainState {
+	if totalWords == 0 && s != nil {
+		panic("a Schedule requires TotalWords to be set")
+	}
+	return &trainState{W2I: w2i, TotalWords: totalWords}
+}
+
+// countWords counts the tokens in a corpus.
+func countWords(c Corpus) (int64, error) {
+	r, err := c.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	var count int64
+	for {
+		sentence, err := r.ReadSentence()
+		if err == io.EOF {
+			return count, nil
+		} else if err != nil {
+			return 0, err
+		}
+		count += int64(len(sentence))
+	}
+}
+
+// AddWord records that a center word was processed and
+// returns the new total.
+func (t *trainState) AddWord() int64 {
+	return atomic.AddInt64(&t.words, 1)
+}
+
+// SetEpoch records the current epoch.
+func (t *trainState) SetEpoch(epoch int) {
+	atomic.StoreInt64(&t.epoch, int64(epoch))
+}
+
+// Fraction computes the fraction of training completed.
+//
+// If TotalWords is set, it is used to measure progress.
+// Otherwise, progress is measured in completed epochs.
+func (t *trainState) Fraction() float64 {
+	if t.TotalWords > 0 {
+		return math.Min(1, float64(atomic.LoadInt64(&t.words))/float64(t.TotalWords))
+	} else if t.NumEpochs > 0 {
+		return float64(atomic.LoadInt64(&t.epoch)) / float64(t.NumEpochs)
+	}
+	return 0
+}
+
+// StepSize scales the initial step size according to the
+// schedule.
+func (t *trainState) StepSize(c anyvec.Creator, initial anyvec.Numeric,
+	s Schedule) anyvec.Numeric {
+	if s == nil {
+		return initial
+	}
+	return c.NumOps().Mul(initial, c.MakeNumeric(s.Scale(t.Fraction())))
+}
+
+// Report calls the status and progress callbacks, making
+// sure that no two calls happen concurrently.
+func (t *trainState) Report(cost, stepSize anyvec.Numeric, status func(anyvec.Numeric),
+	progress func(*Progress)) {
+	if status == nil && progress == nil {
+		return
+	}
+	t.statusLock.Lock()
+	defer t.statusLock.Unlock()
+	if status != nil {
+		status(cost)
+	}
+	if progress != nil {
+		progress(&Progress{
+			Cost:     cost,
+			StepSize: stepSize,
+			Words:    atomic.LoadInt64(&t.words),
+			Epoch:    int(atomic.LoadInt64(&t.epoch)),
+			Fraction: t.Fraction(),
+		})
+	}
+}
@@ -0,0 +1,8 @@
+//go:build race
+
+package word2vec
+
+// raceEnabled is true when the race detector is enabled.
+// Hogwild! training races on purpose, so tests which train
+// on multiple workers are skipped in this case.
+const raceEnabled = true
@@ -0,0 +1,151 @@
+package word2vec
+
+import (
+	"errors"
+	"math"
+
+	"github.com/unixpickle/serializer"
+)
+
+func init() {
+	serializer.RegisterTypedDeserializer((&LinearSchedule{}).SerializerType(),
+		DeserializeLinearSchedule)
+	serializer.RegisterTypedDeserializer((&StepSchedule{}).SerializerType(),
+		DeserializeStepSchedule)
+	serializer.RegisterTypedDeserializer((&CosineSchedule{}).SerializerType(),
+		DeserializeCosineSchedule)
+}
+
+// A Schedule adjusts the step size over the course of
+// training.
+type Schedule interface {
+	serializer.Serializer
+
+	// Scale returns the factor by which the initial step
+	// size should be multiplied once the given fraction of
+	// training (between 0 and 1) has been completed.
+	Scale(progress float64) float64
+}
+
+// A LinearSchedule decays the step size linearly to zero,
+// clipping it at a minimum value.
+// This is the schedule used by the original word2vec.
+type LinearSchedule struct {
+	// Min is the minimum scale.
+	//
+	// If this is 0, 1e-4 is used.
+	Min float64
+}
+
+// DeserializeLinearSchedule deserializes a
+// LinearSchedule.
+func DeserializeLinearSchedule(d []byte) (*LinearSchedule, error) {
+	var res LinearSchedule
+	if err := serializer.DeserializeAny(d, &res.Min); err != nil {
+		return nil, errors.New("deserialize LinearSchedule: " + err.Error())
+	}
+	return &res, nil
+}
+
+// Scale computes the step size scale.
+func (l *LinearSchedule) Scale(progress float64) float64 {
+	min := l.Min
+	if min == 0 {
+		min = 1e-4
+	}
+	return math.Max(min, 1-progress)
+}
+
+// SerializerType returns the unique ID used to serialize
+// a LinearSchedule with the serializer package.
+func (l *LinearSchedule) SerializerType() string {
+	return "github.com/unixpickle/wordembed/word2vec.LinearSchedule"
+}
+
+// Serialize serializes the LinearSchedule.
+func (l *LinearSchedule) Serialize() ([]byte, error) {
+	return serializer.SerializeAny(l.Min)
+}
+
+// A StepSchedule multiplies the step size by a constant
+// factor at evenly spaced points during training.
+type StepSchedule struct {
+	// Drops is the number of times the step size is
+	// decayed.
+	// Training is split into Drops+1 equal phases.
+	Drops int
+
+	// Factor is the multiplier applied at each drop.
+	//
+	// If this is 0, 0.1 is used.
+	Factor float64
+}
+
+// DeserializeStepSchedule deserializes a StepSchedule.
+func DeserializeStepSchedule(d []byte) (*StepSchedule, error) {
+	var res StepSchedule
+	var drops serializer.Int
+	if err := serializer.DeserializeAny(d, &drops, &res.Factor); err != nil {
+		return nil, errors.New("deserialize StepSchedule: " + err.Error())
+	}
+	res.Drops = int(drops)
+	return &res, nil
+}
+
+// Scale computes the step size scale.
+func (s *StepSchedule) Scale(progress float64) float64 {
+	factor := s.Factor
+	if factor == 0 {
+		factor = 0.1
+	}
+	phase := int(progress * float64(s.Drops+1))
+	if phase > s.Drops {
+		phase = s.Drops
+	}
+	return math.Pow(factor, float64(phase))
+}
+
+// SerializerType returns the unique ID used to serialize
+// a StepSchedule with the serializer package.
+func (s *StepSchedule) SerializerType() string {
+	return "github.com/unixpickle/wordembed/word2vec.StepSchedule"
+}
+
+// Serialize serializes the StepSchedule.
+func (s *StepSchedule) Serialize() ([]byte, error) {
+	return serializer.SerializeAny(serializer.Int(s.Drops), s.Factor)
+}
+
+// A CosineSchedule anneals the step size from its initial
+// value to a minimum following half a cosine period.
+type CosineSchedule struct {
+	// Min is the scale at the end of training.
+	Min float64
+}
+
+// DeserializeCosineSchedule deserializes a
+// CosineSchedule.
+func DeserializeCosineSchedule(d []byte) (*CosineSchedule, error) {
+	var res CosineSchedule
+	if err := serializer.DeserializeAny(d, &res.Min); err != nil {
+		return nil, errors.New("deserialize CosineSchedule: " + err.Error())
+	}
+	return &res, nil
+}
+
+// Scale computes the step size scale.
+func (c *CosineSchedule) Scale(progress float64) float64 {
+	progress = math.Min(1, math.Max(0, progress))
+	return c.Min + (1-c.Min)*(1+math.Cos(math.Pi*progress))/2
+}
+
+// SerializerType returns the unique ID used to serialize
+// a CosineSchedule with the serializer package.
+func (c *CosineSchedule) SerializerType() string {
+	return "github.com/unixpickle/wordembed/word2vec.CosineSchedule"
+}
+
+// Serialize serializes the CosineSchedule.
+func (c *CosineSchedule) Serialize() ([]byte, error) {
+	return serializer.SerializeAny(c.Min)
+}
@@ -0,0 +1,128 @@
+package word2vec
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/anyvec/anyvec32"
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/wordembed"
+)
+
+func TestSchedules(t *testing.T) {
+	tests := []struct {
+		Schedule Schedule
+		Progress []float64
+		Expected []float64
+	}{
+		{
+			Schedule: &LinearSchedule{},
+			Progress: []float64{0, 0.25, 1},
+			Expected: []float64{1, 0.75, 1e-4},
+		},
+		{
+			Schedule: &StepSchedule{Drops: 2, Factor: 0.5},
+			Progress: []float64{0, 0.3, 0.4, 0.9, 1},
+			Expected: []float64{1, 1, 0.5, 0.25, 0.25},
+		},
+		{
+			Schedule: &CosineSchedule{Min: 0.1},
+			Progress: []float64{0, 0.5, 1},
+			Expected: []float64{1, 0.55, 0.1},
+		},
+	}
+	for i, test := range tests {
+		for j, progress := range test.Progress {
+			actual := test.Schedule.Scale(progress)
+			if math.Abs(actual-test.Expected[j]) > 1e-8 {
+				t.Errorf("test %d: scale at %f should be %f but got %f", i, progress,
+					test.Expected[j], actual)
+			}
+		}
+	}
+}
+
+func TestScheduleSerialize(t *testing.T) {
+	schedules := []Schedule{
+		&LinearSchedule{Min: 0.01},
+		&StepSchedule{Drops: 3, Factor: 0.3},
+		&CosineSchedule{Min: 0.2},
+	}
+	for _, schedule := range schedules {
+		data, err := serializer.SerializeAny(schedule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actual Schedule
+		if err := serializer.DeserializeAny(data, &actual); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(actual, schedule) {
+			t.Errorf("expected %v but got %v", schedule, actual)
+		}
+	}
+}
+
+func TestTrainProgress(t *testing.T) {
+	corpus := SliceCorpus{{"a", "b", "c", "a"}}
+	counts := wordembed.TokenCounts{"a": 2, "b": 1, "c": 1}
+	c := anyvec32.CurrentCreator()
+	sampler := NewNegSampler(c, counts, 4)
+	var progress []*Progress
+	trainer := &SkipGram{
+		Net:          NewNet(c, len(sampler.Words), 4, 0),
+		NegSampler:   sampler,
+		StepSize:     c.MakeNumeric(-0.1),
+		Schedule:     &LinearSchedule{},
+		TotalWords:   8,
+		NumWorkers:   1,
+		ProgressFunc: func(p *Progress) { progress = append(progress, p) },
+	}
+	if err := trainer.TrainCorpus(corpus, 2); err != nil {
+		t.Fatal(err)
+	}
+	if len(progress) != 8 {
+		t.Fatalf("expected 8 progress reports but got %d", len(progress))
+	}
+	for i, p := range progress {
+		if p.Words != int64(i+1) || p.Epoch != i/4 {
+			t.Errorf("report %d: unexpected words %d or epoch %d", i, p.Words, p.Epoch)
+		}
+		if p.Fraction != float64(i+1)/8 {
+			t.Errorf("report %d: unexpected fraction %f", i, p.Fraction)
+		}
+		expected := -0.1 * (1 - float64(i)/8)
+		if step := p.StepSize.(float32); math.Abs(float64(step)-expected) > 1e-6 {
+			t.Errorf("report %d: expected step %f but got %f", i, expected, step)
+		}
+	}
+}
+
+func TestTrainScheduleSingleEpoch(t *testing.T) {
+	corpus := SliceCorpus{{"a", "b", "c", "a"}, {"b", "a", "c"}}
+	counts := wordembed.TokenCounts{"a": 3, "b": 2, "c": 2}
+	c := anyvec32.CurrentCreator()
+	sampler := NewNegSampler(c, counts, 4)
+	var steps []float64
+	trainer := &SkipGram{
+		Net:          NewNet(c, len(sampler.Words), 4, 0),
+		NegSampler:   sampler,
+		StepSize:     c.MakeNumeric(-0.1),
+		Schedule:     &LinearSchedule{},
+		NumWorkers:   1,
+		ProgressFunc: func(p *Progress) { steps = append(steps, float64(p.StepSize.(float32))) },
+	}
+	if err := trainer.TrainCorpus(corpus, 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 7 {
+		t.Fatalf("expected 7 steps but got %d", len(steps))
+	}
+	for i, step := range steps {
+		expected := -0.1 * (1 - float64(i)/7)
+		if math.Abs(step-expected) > 1e-6 {
+			t.Errorf("step %d: expected %f but got %f", i, expected, step)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package word2vec
 
 import (
 	"math/rand"
-	"sort"
 
 	"github.com/unixpickle/anyvec"
 )
@@ -16,9 +15,44 @@ type SkipGram struct {
 	Hierarchy Hierarchy
 	Samples   []*Sample
 
+	// NegSampler, if non-nil, is used to train the model
+	// with negative sampling instead of hierarchical
+	// softmax.
+	// In this case, Hierarchy is not used and may be nil.
+	NegSampler *NegSampler
+
+	// Subwords, if non-nil, is used to represent each
+	// center word as the sum of its own encoder row and
+	// the rows for its character n-grams.
+	// In this case, the Net's input size should be
+	// Subwords.NumInputs().
+	Subwords *Subwords
+
+	// Subsampler, if non-nil, is used to randomly discard
+	// frequent words from both the center word and the
+	// context of each sample.
+	// Its threshold is recorded in the Net.
+	Subsampler *Subsampler
+
 	// StepSize should be negative for gradient descent.
 	StepSize anyvec.Numeric
 
+	// Schedule, if non-nil, is used to adjust the step size
+	// as training progresses.
+	Schedule Schedule
+
+	// TotalWords is the total number of center words which
+	// will be processed during training.
+	// It is used to measure the progress of training.
+	//
+	// If this is 0 and a Schedule is used, TrainCorpus
+	// counts the words in the corpus before training, and
+	// Train panics.
+	// If this is 0 and no Schedule is used, TrainCorpus
+	// measures progress in completed epochs, and Train
+	// cannot measure progress.
+	TotalWords int64
+
 	// The minimum and maximum number of neighbors to use as
 	// context during training.
 	//
@@ -27,50 +61,126 @@ type SkipGram struct {
 	MinDist int
 	MaxDist int
 
+	// NumWorkers is the number of goroutines to use for
+	// training.
+	// Workers update the parameters concurrently without
+	// locking, in the style of Hogwild!.
+	//
+	// If this is 0, runtime.GOMAXPROCS(0) is used.
+	NumWorkers int
+
 	// StatusFunc, if non-nil, is called after every training
 	// iteration with the cost from that iteration.
+	//
+	// Calls to StatusFunc are never concurrent, even when
+	// multiple workers are used.
 	StatusFunc func(lastCost anyvec.Numeric)
+
+	// ProgressFunc, if non-nil, is called after every
+	// training iteration with detailed progress info.
+	//
+	// Calls to ProgressFunc are never concurrent.
+	ProgressFunc func(p *Progress)
 }
 
 // Train trains the skip-gram model until the done channel
 // is closed.
+//
+// Training is performed on NumWorkers goroutines which
+// update the parameters without any locking.
 func (s *SkipGram) Train(done <-chan struct{}) {
-	w2i := s.wordToIndex()
-	creator := s.Net.Encoder.Vector.Creator()
-	one := creator.MakeNumeric(1)
+	state := newSampleState(vocabIndices(s.Hierarchy, s.NegSampler), s.TotalWords,
+		s.Schedule)
+	s.Subsampler.record(s.Net)
+	runWorkers(s.NumWorkers, done, func() {
+		sample := s.Samples[rand.Intn(len(s.Samples))]
+		s.trainRaw(state, sample)
+	})
+}
 
-	for {
-		select {
-		case <-done:
-			return
-		default:
+// TrainCorpus trains the skip-gram model by streaming the
+// sentences of a corpus, making the given number of passes
+// over the corpus.
+//
+// Samples are generated on the fly from each sentence,
+// and the Samples field is not used.
+//
+// Training is performed on NumWorkers goroutines which
+// update the parameters without any locking.
+func (s *SkipGram) TrainCorpus(corpus Corpus, epochs int) error {
+	state, err := newCorpusState(vocabIndices(s.Hierarchy, s.NegSampler), corpus, epochs,
+		s.TotalWords, s.Schedule)
+	if err != nil {
+		return err
+	}
+	s.Subsampler.record(s.Net)
+	return streamCorpus(corpus, epochs, s.NumWorkers, func(epoch int, sentence []string) {
+		state.SetEpoch(epoch)
+		for _, sample := range AllSamples(sentence) {
+			s.trainRaw(state, sample)
 		}
+	})
+}
 
-		sample := s.Samples[rand.Intn(len(s.Samples))]
-		radius := rand.Intn(s.MaxDist-s.MinDist+1) + s.MinDist
-		sample = sample.Trim(radius)
-
-		in := map[int]anyvec.Numeric{w2i[sample.Word]: one}
-		allWords := append(append([]string{}, sample.Left...), sample.Right...)
-		paths := s.Hierarchy.Paths(allWords)
+// trainRaw trims and subsamples a sample, trains on the
+// result, and reports the cost to the callbacks.
+func (s *SkipGram) trainRaw(state *trainState, sample *Sample) {
+	creator := s.Net.Encoder.Vector.Creator()
+	stepSize := state.StepSize(creator, s.StepSize, s.Schedule)
+	state.AddWord()
 
-		cost := s.Net.Step(in, paths, s.StepSize)
-		if s.StatusFunc != nil {
-			s.StatusFunc(cost)
+	sample = sample.Trim(randomRadius(s.MinDist, s.MaxDist))
+	if s.Subsampler != nil {
+		if sample = s.Subsampler.Apply(sample); sample == nil {
+			return
 		}
 	}
+	if cost, ok := s.trainSample(state.W2I, sample, stepSize); ok {
+		state.Report(cost, stepSize, s.StatusFunc, s.ProgressFunc)
+	}
 }
 
-func (s *SkipGram) wordToIndex() map[string]int {
-	var words []string
-	for x := range s.Hierarchy {
-		words = append(words, x)
+// trainSample performs a training step on a sample which
+// has already been trimmed and subsampled.
+//
+// Context words outside of the vocabulary are ignored.
+// If the center word is outside of the vocabulary (and
+// cannot be represented by subwords), or if the sample
+// has no usable context, false is returned and no step is
+// taken.
+func (s *SkipGram) trainSample(w2i map[string]int, sample *Sample,
+	stepSize anyvec.Numeric) (anyvec.Numeric, bool) {
+	creator := s.Net.Encoder.Vector.Creator()
+	var in map[int]anyvec.Numeric
+	if s.Subwords != nil {
+		in = s.Subwords.Input(creator, sample.Word)
+		if len(in) == 0 {
+			return nil, false
+		}
+	} else {
+		idx, ok := w2i[sample.Word]
+		if !ok {
+			return nil, false
+		}
+		in = map[int]anyvec.Numeric{idx: creator.MakeNumeric(1)}
+	}
+	var context []string
+	var targets []int
+	for _, words := range [][]string{sample.Left, sample.Right} {
+		for _, word := range words {
+			if idx, ok := w2i[word]; ok {
+				context = append(context, word)
+				targets = append(targets, idx)
+			}
+		}
+	}
+	if len(context) == 0 {
+		return nil, false
 	}
-	sort.Strings(words)
 
-	res := map[string]int{}
-	for index, word := range words {
-		res[word] = index
+	if s.NegSampler != nil {
+		return s.NegSampler.Step(s.Net, in, targets, stepSize), true
 	}
-	return res
+	paths := s.Hierarchy.Paths(context)
+	return s.Net.Step(in, paths, stepSize), true
 }
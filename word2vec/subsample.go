@@ -0,0 +1,140 @@
+package word2vec
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/rand"
+
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/wordembed"
+)
+
+func init() {
+	var s Subsampler
+	serializer.RegisterTypedDeserializer(s.SerializerType(), DeserializeSubsampler)
+}
+
+const defaultSubsampleThreshold = 1e-3
+
+// A Subsampler randomly discards occurrences of frequent
+// words, as described in the word2vec paper.
+//
+// A word with frequency f is kept with probability
+// (sqrt(f/t)+1)*t/f, where t is the threshold.
+// This is the formula from the original word2vec code.
+type Subsampler struct {
+	// Threshold is the frequency above which words are
+	// aggressively subsampled.
+	Threshold float64
+
+	// KeepProbs maps each word which may be discarded to
+	// the probability that an occurrence of it is kept.
+	// Other words are always kept.
+	//
+	// Only words much more frequent than the threshold are
+	// included, so this is small even for large
+	// vocabularies.
+	KeepProbs map[string]float64
+}
+
+// DeserializeSubsampler deserializes a Subsampler.
+func DeserializeSubsampler(d []byte) (*Subsampler, error) {
+	var res Subsampler
+	var probs serializer.Bytes
+	if err := serializer.DeserializeAny(d, &res.Threshold, &probs); err != nil {
+		return nil, errors.New("deserialize Subsampler: " + err.Error())
+	}
+	if err := json.Unmarshal(probs, &res.KeepProbs); err != nil {
+		return nil, errors.New("deserialize Subsampler: " + err.Error())
+	}
+	return &res, nil
+}
+
+// NewSubsampler creates a Subsampler from token counts.
+//
+// If threshold is 0, 1e-3 is used.
+func NewSubsampler(counts wordembed.TokenCounts, threshold float64) *Subsampler {
+	if threshold == 0 {
+		threshold = defaultSubsampleThreshold
+	}
+	var total int
+	for _, count := range counts {
+		total += count
+	}
+	res := &Subsampler{Threshold: threshold, KeepProbs: map[string]float64{}}
+	for word, count := range counts {
+		if count == 0 {
+			continue
+		}
+		freq := float64(count) / float64(total)
+		if prob := (math.Sqrt(freq/threshold) + 1) * threshold / freq; prob < 1 {
+			res.KeepProbs[word] = prob
+		}
+	}
+	return res
+}
+
+// KeepProb returns the probability that an occurrence of
+// the word is kept.
+func (s *Subsampler) KeepProb(word string) float64 {
+	if prob, ok := s.KeepProbs[word]; ok {
+		return prob
+	}
+	return 1
+}
+
+// Keep randomly decides whether or not to keep an
+// occurrence of the word.
+func (s *Subsampler) Keep(word string) bool {
+	return rand.Float64() < s.KeepProb(word)
+}
+
+// Filter creates a new list of words with some words
+// randomly discarded.
+func (s *Subsampler) Filter(words []string) []string {
+	var res []string
+	for _, word := range words {
+		if s.Keep(word) {
+			res = append(res, word)
+		}
+	}
+	return res
+}
+
+// Apply subsamples the center word and the context of a
+// sample.
+//
+// If the center word is discarded, nil is returned.
+// Otherwise, a new sample is returned with some context
+// words randomly discarded.
+func (s *Subsampler) Apply(sample *Sample) *Sample {
+	if !s.Keep(sample.Word) {
+		return nil
+	}
+	return &Sample{
+		Left:  s.Filter(sample.Left),
+		Word:  sample.Word,
+		Right: s.Filter(sample.Right),
+	}
+}
+
+// SerializerType returns the unique ID used to serialize
+// a Subsampler with the serializer package.
+func (s *Subsampler) SerializerType() string {
+	return "github.com/unixpickle/wordembed/word2vec.Subsampler"
+}
+
+// Serialize serializes the Subsampler.
+func (s *Subsampler) Serialize() ([]byte, error) {
+	probs, _ := json.Marshal(s.KeepProbs)
+	return serializer.SerializeAny(s.Threshold, serializer.Bytes(probs))
+}
+
+// record saves the threshold with a Net, if the
+// Subsampler is non-nil.
+func (s *Subsampler) record(net *Net) {
+	if s != nil {
+		net.SubsampleThreshold = s.Threshold
+	}
+}
@@ -0,0 +1,65 @@
+package word2vec
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/anyvec/anyvec32"
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/wordembed"
+)
+
+func TestSubsamplerKeepProb(t *testing.T) {
+	s := NewSubsampler(wordembed.TokenCounts{"a": 1, "b": 99900, "c": 99}, 0)
+	if prob := s.KeepProb("a"); prob != 1 {
+		t.Errorf("rare word should be kept but got probability %f", prob)
+	}
+	if prob := s.KeepProb("unknown"); prob != 1 {
+		t.Errorf("unknown word should be kept but got probability %f", prob)
+	}
+	freq := 0.999
+	expected := (math.Sqrt(freq/1e-3) + 1) * 1e-3 / freq
+	if prob := s.KeepProb("b"); math.Abs(prob-expected) > 1e-8 {
+		t.Errorf("expected probability %f but got %f", expected, prob)
+	}
+	if s.Threshold != 1e-3 {
+		t.Errorf("expected default threshold but got %f", s.Threshold)
+	}
+	if len(s.KeepProbs) != 1 {
+		t.Errorf("expected only one stored probability but got %v", s.KeepProbs)
+	}
+}
+
+func TestSubsamplerSerialize(t *testing.T) {
+	s := NewSubsampler(wordembed.TokenCounts{"a": 1, "b": 99900, "c": 99}, 1e-4)
+	data, err := serializer.SerializeAny(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s1 *Subsampler
+	if err := serializer.DeserializeAny(data, &s1); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s, s1) {
+		t.Error("invalid result")
+	}
+}
+
+func TestSubsamplerRecord(t *testing.T) {
+	counts := wordembed.TokenCounts{"a": 2, "b": 1, "c": 1}
+	c := anyvec32.CurrentCreator()
+	sampler := NewNegSampler(c, counts, 4)
+	trainer := &SkipGram{
+		Net:        NewNet(c, len(sampler.Words), 4, 0),
+		NegSampler: sampler,
+		Subsampler: NewSubsampler(counts, 1e-2),
+		StepSize:   c.MakeNumeric(-0.1),
+	}
+	if err := trainer.TrainCorpus(SliceCorpus{{"a", "b", "c", "a"}}, 1); err != nil {
+		t.Fatal(err)
+	}
+	if trainer.Net.SubsampleThreshold != 1e-2 {
+		t.Errorf("expected threshold 1e-2 but got %f", trainer.Net.SubsampleThreshold)
+	}
+}
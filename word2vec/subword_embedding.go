@@ -0,0 +1,116 @@
+package word2vec
+
+import (
+	"errors"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvecsave"
+	"github.com/unixpickle/serializer"
+)
+
+func init() {
+	var s SubwordEmbedding
+	serializer.RegisterTypedDeserializer(s.SerializerType(), DeserializeSubwordEmbedding)
+}
+
+// SubwordEmbedding is a word embedding which represents
+// words as bags of character n-grams.
+// It can produce vectors for words which were never seen
+// during training.
+//
+// SubwordEmbedding implements wordembed.Embedding.
+// Token IDs refer to words in Subwords.Words.
+type SubwordEmbedding struct {
+	Subwords *Subwords
+
+	// Matrix is the encoder matrix, with one row per input
+	// ID of Subwords.
+	Matrix *anydiff.Var
+
+	// Vocab stores precomputed vectors for every word in
+	// the vocabulary.
+	// It is used for EmbedID and Lookup.
+	Vocab *Embedding
+}
+
+// DeserializeSubwordEmbedding deserializes a
+// SubwordEmbedding.
+func DeserializeSubwordEmbedding(d []byte) (*SubwordEmbedding, error) {
+	var sub *Subwords
+	var vec *anyvecsave.S
+	if err := serializer.DeserializeAny(d, &sub, &vec); err != nil {
+		return nil, errors.New("deserialize SubwordEmbedding: " + err.Error())
+	}
+	return NewSubwordEmbedding(sub, anydiff.NewVar(vec.Vector)), nil
+}
+
+// NewSubwordEmbedding creates a SubwordEmbedding from the
+// encoder matrix of a Net trained with subwords.
+func NewSubwordEmbedding(sub *Subwords, mat *anydiff.Var) *SubwordEmbedding {
+	res := &SubwordEmbedding{
+		Subwords: sub,
+		Matrix:   mat,
+	}
+	var rows []anyvec.Vector
+	for _, word := range sub.Words {
+		rows = append(rows, res.Embed(word))
+	}
+	res.Vocab = &Embedding{
+		Model: &Embed{
+			Matrix: anydiff.NewVar(mat.Vector.Creator().Concat(rows...)),
+			Words:  sub.Words,
+		},
+	}
+	return res
+}
+
+// Dim returns the dimensionality of the embedding.
+func (s *SubwordEmbedding) Dim() int {
+	return s.Matrix.Vector.Len() / s.Subwords.NumInputs()
+}
+
+// Embed returns the embedding for the token.
+//
+// If the token is not in the vocabulary, its embedding
+// is synthesized from its n-grams.
+func (s *SubwordEmbedding) Embed(token string) anyvec.Vector {
+	in := s.Subwords.Input(s.Matrix.Vector.Creator(), token)
+	return encodeSparse(s.Matrix.Vector, s.Dim(), in)
+}
+
+// EmbedID returns the embedding for the token ID.
+//
+// The unknown token ID has a zero embedding.
+func (s *SubwordEmbedding) EmbedID(id int) anyvec.Vector {
+	return s.Vocab.EmbedID(id)
+}
+
+// Lookup finds the n closest token IDs to the given
+// vector, using cosine similarity.
+// For each ID, it also returns the similarity.
+func (s *SubwordEmbedding) Lookup(vec anyvec.Vector, n int) ([]int, []anyvec.Numeric) {
+	return s.Vocab.Lookup(vec, n)
+}
+
+// Token returns the token for the token ID.
+func (s *SubwordEmbedding) Token(id int) string {
+	return s.Vocab.Token(id)
+}
+
+// SerializerType returns the unique ID used to serialize
+// a SubwordEmbedding with the serializer package.
+func (s *SubwordEmbedding) SerializerType() string {
+	return "github.com/unixpickle/wordembed/word2vec.SubwordEmbedding"
+}
+
+// Serialize serializes the SubwordEmbedding.
+//
+// The precomputed vocabulary vectors are not serialized,
+// since they are recomputed during deserialization.
+func (s *SubwordEmbedding) Serialize() ([]byte, error) {
+	return serializer.SerializeAny(
+		s.Subwords,
+		&anyvecsave.S{Vector: s.Matrix.Vector},
+	)
+}
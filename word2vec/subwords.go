@@ -0,0 +1,150 @@
+package word2vec
+
+import (
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/wordembed"
+)
+
+func init() {
+	var s Subwords
+	serializer.RegisterTypedDeserializer(s.SerializerType(), DeserializeSubwords)
+}
+
+const (
+	defaultMinNGram   = 3
+	defaultMaxNGram   = 6
+	defaultNumBuckets = 2000000
+)
+
+// Subwords represents words as bags of character n-grams,
+// as in fastText.
+//
+// Each word corresponds to a set of input IDs for a Net:
+// the word's own ID (if it is in the vocabulary) and one
+// ID per hashed n-gram.
+// The first len(Words) input IDs are for whole words, and
+// the remaining IDs are hash buckets for n-grams.
+type Subwords struct {
+	// Words is the sorted vocabulary.
+	Words []string
+
+	// MinN and MaxN bound the length of character n-grams.
+	// Before n-grams are extracted, words are wrapped in
+	// "<" and ">" to mark their boundaries.
+	//
+	// If these are 0, 3 and 6 are used, respectively.
+	MinN int
+	MaxN int
+
+	// Buckets is the number of hash buckets for n-grams.
+	//
+	// If this is 0, 2000000 is used.
+	Buckets int
+}
+
+// DeserializeSubwords deserializes a Subwords.
+func DeserializeSubwords(d []byte) (*Subwords, error) {
+	var wordList serializer.Bytes
+	var minN, maxN, buckets serializer.Int
+	if err := serializer.DeserializeAny(d, &wordList, &minN, &maxN, &buckets); err != nil {
+		return nil, errors.New("deserialize Subwords: " + err.Error())
+	}
+	var words []string
+	if err := json.Unmarshal(wordList, &words); err != nil {
+		return nil, errors.New("deserialize Subwords: " + err.Error())
+	}
+	return &Subwords{
+		Words:   words,
+		MinN:    int(minN),
+		MaxN:    int(maxN),
+		Buckets: int(buckets),
+	}, nil
+}
+
+// NumInputs returns the number of input IDs, which should
+// be the input size of the Net being trained.
+func (s *Subwords) NumInputs() int {
+	return len(s.Words) + s.numBuckets()
+}
+
+// NGrams returns the character n-grams of a word.
+func (s *Subwords) NGrams(word string) []string {
+	minN, maxN := s.MinN, s.MaxN
+	if minN == 0 {
+		minN = defaultMinNGram
+	}
+	if maxN == 0 {
+		maxN = defaultMaxNGram
+	}
+	chars := []rune("<" + word + ">")
+	var res []string
+	for n := minN; n <= maxN; n++ {
+		for i := 0; i+n <= len(chars); i++ {
+			res = append(res, string(chars[i:i+n]))
+		}
+	}
+	return res
+}
+
+// IDs returns the input IDs for a word.
+// If the word is in the vocabulary, its own ID comes
+// first, followed by the IDs of its n-grams.
+func (s *Subwords) IDs(word string) []int {
+	var res []int
+	if wordembed.TokenSet(s.Words).Contains(word) {
+		res = append(res, wordembed.TokenSet(s.Words).ID(word))
+	}
+	buckets := uint32(s.numBuckets())
+	for _, ngram := range s.NGrams(word) {
+		hash := fnv.New32a()
+		hash.Write([]byte(ngram))
+		res = append(res, len(s.Words)+int(hash.Sum32()%buckets))
+	}
+	return res
+}
+
+// Input creates a sparse input for a Net which sums the
+// rows for all of a word's input IDs.
+//
+// This can be used for words outside of the vocabulary,
+// in which case only n-grams are used.
+func (s *Subwords) Input(c anyvec.Creator, word string) map[int]anyvec.Numeric {
+	counts := map[int]int{}
+	for _, id := range s.IDs(word) {
+		counts[id]++
+	}
+	res := map[int]anyvec.Numeric{}
+	for id, count := range counts {
+		res[id] = c.MakeNumeric(float64(count))
+	}
+	return res
+}
+
+// SerializerType returns the unique ID used to serialize
+// a Subwords with the serializer package.
+func (s *Subwords) SerializerType() string {
+	return "github.com/unixpickle/wordembed/word2vec.Subwords"
+}
+
+// Serialize serializes the Subwords.
+func (s *Subwords) Serialize() ([]byte, error) {
+	data, _ := json.Marshal(s.Words)
+	return serializer.SerializeAny(
+		serializer.Bytes(data),
+		serializer.Int(s.MinN),
+		serializer.Int(s.MaxN),
+		serializer.Int(s.Buckets),
+	)
+}
+
+func (s *Subwords) numBuckets() int {
+	if s.Buckets == 0 {
+		return defaultNumBuckets
+	}
+	return s.Buckets
+}
@@ -0,0 +1,85 @@
+package word2vec
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvec32"
+	"github.com/unixpickle/serializer"
+)
+
+func TestSubwordsNGrams(t *testing.T) {
+	s := &Subwords{MinN: 3, MaxN: 4}
+	actual := s.NGrams("cat")
+	expected := []string{"<ca", "cat", "at>", "<cat", "cat>"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v but got %v", expected, actual)
+	}
+}
+
+func TestSubwordsInput(t *testing.T) {
+	s := &Subwords{Words: []string{"a", "cat", "dog"}, MinN: 3, MaxN: 3, Buckets: 10}
+	c := anyvec32.CurrentCreator()
+
+	in := s.Input(c, "cat")
+	ids := s.IDs("cat")
+	if len(ids) != 4 || ids[0] != 1 {
+		t.Fatalf("unexpected IDs: %v", ids)
+	}
+	var total float32
+	for id, weight := range in {
+		if id < 0 || id >= s.NumInputs() {
+			t.Errorf("ID out of range: %d", id)
+		}
+		total += weight.(float32)
+	}
+	if total != float32(len(ids)) {
+		t.Errorf("weights should sum to %d but got %f", len(ids), total)
+	}
+
+	for _, id := range s.IDs("cats") {
+		if id < len(s.Words) {
+			t.Errorf("unknown word should not use word ID %d", id)
+		}
+	}
+}
+
+func TestSubwordEmbedding(t *testing.T) {
+	sub := &Subwords{Words: []string{"a", "cat", "dog"}, MinN: 3, MaxN: 3, Buckets: 10}
+	c := anyvec32.CurrentCreator()
+	net := NewNet(c, sub.NumInputs(), 4, 0)
+	e := NewSubwordEmbedding(sub, net.Encoder)
+
+	if e.Dim() != 4 {
+		t.Errorf("expected dim 4 but got %d", e.Dim())
+	}
+	expected := encodeSparse(net.Encoder.Vector, 4, sub.Input(c, "dog")).Data()
+	if actual := e.EmbedID(2).Data(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v but got %v", expected, actual)
+	}
+	if actual := e.Embed("dog").Data(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v but got %v", expected, actual)
+	}
+	unseen := e.Embed("dogs")
+	if anyvec.Norm(unseen).(float32) == 0 {
+		t.Error("unseen word should have a non-zero embedding")
+	}
+	ids, _ := e.Lookup(unseen, 1)
+	if e.Token(ids[0]) == "" {
+		t.Error("lookup should return a known word")
+	}
+
+	data, err := serializer.SerializeAny(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var e1 *SubwordEmbedding
+	if err := serializer.DeserializeAny(data, &e1); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(e.Subwords, e1.Subwords) || !reflect.DeepEqual(e.Matrix, e1.Matrix) ||
+		!reflect.DeepEqual(e.Vocab.Model, e1.Vocab.Model) {
+		t.Error("invalid result")
+	}
+}